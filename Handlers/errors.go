@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"insider-league/Models"
+	"insider-league/database"
+	"insider-league/middleware"
+)
+
+// Error codes sent in the code member of problem responses. They are part of
+// the API, so existing codes must not be renamed.
+const (
+	CodeInvalidJSON      = "invalid_json"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeValidationFailed = "validation_failed"
+	CodeBodyTooLarge     = "body_too_large"
+	CodeNotFound         = "not_found"
+	CodeNoLeague         = "no_league"
+	CodeLeagueNotFound   = "league_not_found"
+	CodeTeamNotFound     = "team_not_found"
+	CodeMatchNotFound    = "match_not_found"
+	CodeConflict         = "conflict"
+	CodeTeamExists       = "team_exists"
+	CodeNotEnoughTeams   = "not_enough_teams"
+	CodeSeasonComplete   = "season_complete"
+	CodeRatingsDisabled  = "ratings_disabled"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeRequestCanceled  = "request_canceled"
+	CodeInternal         = "internal_error"
+)
+
+// writeProblem sends an RFC 7807 problem response
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	sendProblem(w, r, models.NewProblem(status, code, detail))
+}
+
+// sendProblem fills in the request details of a problem and sends it
+func sendProblem(w http.ResponseWriter, r *http.Request, problem models.Problem) {
+	problem.Instance = r.URL.Path
+	problem.RequestID = middleware.RequestIDFrom(r.Context())
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Printf("failed to encode problem: %v", err)
+	}
+}
+
+// writeError sends the problem matching a repository error. Errors of no
+// known kind are logged and reported as internal errors with the given
+// detail, so storage details never reach the client.
+func writeError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	switch {
+	case r.Context().Err() != nil:
+		// The client went away or the server gave up on the request while
+		// draining; the work was stopped on purpose
+		writeProblem(w, r, http.StatusServiceUnavailable, CodeRequestCanceled, detail+": request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		// Work bounded by its own deadline ran out of time
+		writeProblem(w, r, http.StatusServiceUnavailable, CodeRequestCanceled, detail+": took too long")
+	case errors.Is(err, database.ErrSeasonComplete):
+		writeProblem(w, r, http.StatusConflict, CodeSeasonComplete, err.Error())
+	case errors.Is(err, database.ErrNotFound):
+		writeProblem(w, r, http.StatusNotFound, CodeNotFound, err.Error())
+	case errors.Is(err, database.ErrConflict):
+		writeProblem(w, r, http.StatusConflict, CodeConflict, err.Error())
+	case errors.Is(err, database.ErrValidation):
+		writeProblem(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+	default:
+		log.Printf("%s: %v request_id=%s", detail, err, middleware.RequestIDFrom(r.Context()))
+		writeProblem(w, r, http.StatusInternalServerError, CodeInternal, detail)
+	}
+}
+
+// WithProblemFallback answers the requests mux has no route for with a
+// problem response instead of its plain-text 404 and 405 errors
+func WithProblemFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Let the mux decide between 404 and 405 and set Allow, then
+		// replace its body
+		rec := &headerRecorder{header: make(http.Header)}
+		h.ServeHTTP(rec, r)
+
+		switch rec.status {
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			writeProblem(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed,
+				r.Method+" is not supported for "+r.URL.Path)
+		case http.StatusNotFound:
+			writeProblem(w, r, http.StatusNotFound, CodeNotFound, "No route matches "+r.URL.Path)
+		default:
+			// Redirects to the canonical path and the like
+			for key, values := range rec.header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(rec.status)
+		}
+	})
+}
+
+// headerRecorder keeps the headers and status written by a handler and
+// drops the body
+type headerRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *headerRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *headerRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *headerRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return len(b), nil
+}
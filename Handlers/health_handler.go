@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"insider-league/database"
+)
+
+// readinessTimeout bounds the database checks of a readiness probe
+const readinessTimeout = 2 * time.Second
+
+// HealthHandler answers liveness and readiness probes
+type HealthHandler struct {
+	db      *sql.DB // nil when the data is kept in memory
+	version string
+	started time.Time
+}
+
+// NewHealthHandler creates a handler reporting on db, which is nil for the
+// in-memory store. version is the build version set at link time.
+func NewHealthHandler(db *sql.DB, version string) *HealthHandler {
+	return &HealthHandler{
+		db:      db,
+		version: version,
+		started: time.Now(),
+	}
+}
+
+// HealthResponse is the body of both probes; Database is only filled in by
+// the readiness probe when PostgreSQL is used
+type HealthResponse struct {
+	Status        string          `json:"status"`
+	Version       string          `json:"version"`
+	Uptime        string          `json:"uptime"`
+	UptimeSeconds int64           `json:"uptime_seconds"`
+	Storage       string          `json:"storage"`
+	Database      *DatabaseHealth `json:"database,omitempty"`
+}
+
+// DatabaseHealth reports the database checks made by the readiness probe
+type DatabaseHealth struct {
+	Status              string     `json:"status"`
+	Error               string     `json:"error,omitempty"`
+	LatencyMs           float64    `json:"latency_ms"`
+	SchemaVersion       int        `json:"schema_version"`
+	LatestSchemaVersion int        `json:"latest_schema_version"`
+	Pool                PoolHealth `json:"pool"`
+}
+
+// PoolHealth is a snapshot of the connection pool
+type PoolHealth struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+}
+
+// Live - GET /api/health/live
+// Reports that the process is up; it never checks dependencies, so a slow
+// database does not get the server restarted
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.response("ok"))
+}
+
+// Ready - GET /api/health/ready
+// Reports whether the server can handle requests: the database answers a
+// ping in time and its schema is fully migrated. Responds 503 otherwise.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	response := h.response("ok")
+	if h.db == nil {
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	response.Database = h.checkDatabase(ctx)
+	status := http.StatusOK
+	if response.Database.Status != "ok" {
+		response.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, response)
+}
+
+// response fills in the details shared by both probes
+func (h *HealthHandler) response(status string) HealthResponse {
+	uptime := time.Since(h.started)
+	storage := "postgres"
+	if h.db == nil {
+		storage = "memory"
+	}
+
+	return HealthResponse{
+		Status:        status,
+		Version:       h.version,
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Storage:       storage,
+	}
+}
+
+// checkDatabase pings the database and compares its schema version with the
+// migrations built into the binary
+func (h *HealthHandler) checkDatabase(ctx context.Context) *DatabaseHealth {
+	stats := h.db.Stats()
+	health := &DatabaseHealth{
+		Status: "ok",
+		Pool: PoolHealth{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDuration:       stats.WaitDuration.String(),
+		},
+	}
+
+	start := time.Now()
+	err := h.db.PingContext(ctx)
+	health.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		health.Status = "unavailable"
+		health.Error = "ping failed: " + err.Error()
+		return health
+	}
+
+	if health.SchemaVersion, err = database.SchemaVersion(ctx, h.db); err != nil {
+		health.Status = "unavailable"
+		health.Error = err.Error()
+		return health
+	}
+	if health.LatestSchemaVersion, err = database.LatestSchemaVersion(); err != nil {
+		health.Status = "unavailable"
+		health.Error = err.Error()
+		return health
+	}
+	if health.SchemaVersion < health.LatestSchemaVersion {
+		health.Status = "unavailable"
+		health.Error = "database schema is not fully migrated"
+	}
+
+	return health
+}
@@ -1,12 +1,16 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"insider-league/Models"
 	"insider-league/Services"
@@ -14,739 +18,1161 @@ import (
 )
 
 type LeagueHandler struct {
-	league   *services.GenerateLeague
-	repo     *database.TeamRepository
-	leagueID int
+	repo database.Repository
+
+	// simulationTimeout bounds CPU-bound work such as predictions, so it
+	// stops before the server gives up on writing the response; 0 disables it
+	simulationTimeout time.Duration
+
+	// defaultLeagueID is the league served by the /api/league/* aliases.
+	// defaultVersion changes with every update, so a lookup that ran without
+	// the lock only fills the cache when nothing changed meanwhile.
+	mu              sync.Mutex
+	defaultLeagueID int
+	defaultVersion  int
 }
 
-func NewLeagueHandler() *LeagueHandler {
+// NewLeagueHandler creates a handler backed by repo, either the PostgreSQL
+// TeamRepository or a MemoryRepository. Simulations stop after
+// simulationTimeout, which should be below the server's write timeout; 0
+// lets them run until the request is canceled.
+func NewLeagueHandler(repo database.Repository, simulationTimeout time.Duration) *LeagueHandler {
 	return &LeagueHandler{
-		repo: &database.TeamRepository{},
+		repo:              repo,
+		simulationTimeout: simulationTimeout,
 	}
 }
 
 // CreateLeague - POST /api/league
+// Creates a league and makes it the default league for the /api/league routes
 func (h *LeagueHandler) CreateLeague(w http.ResponseWriter, r *http.Request) {
-	// Get teams from database 
-	dbTeams, err := h.repo.GetAllTeams()
+	h.createLeague(w, r, true)
+}
+
+// AddLeague - POST /api/leagues
+// Creates a league without changing the default league
+func (h *LeagueHandler) AddLeague(w http.ResponseWriter, r *http.Request) {
+	h.createLeague(w, r, false)
+}
+
+// createLeague creates a league from a chosen subset of the stored teams.
+// Accepts an optional JSON body:
+// {"name": "Premier League", "team_ids": [1, 2, 3], "legs": 2, "seed": 42, "engine": "poisson",
+//
+//	"rules": {"points_win": 3, "tie_breakers": ["head_to_head_points", "goal_difference"]},
+//	"rating_system": "elo", "simulate_with_ratings": true}
+func (h *LeagueHandler) createLeague(w http.ResponseWriter, r *http.Request, makeDefault bool) {
+	// Rules are decoded over the defaults, so fields left out keep them
+	defaultRules := services.DefaultRules()
+	leagueRequest := models.CreateLeagueRequest{Rules: &defaultRules}
+
+	// The body is optional, an empty one keeps the defaults
+	if !decodeRequest(w, r, &leagueRequest, true) {
+		return
+	}
+
+	rules := defaultRules
+	if leagueRequest.Rules != nil {
+		rules = *leagueRequest.Rules
+	}
+	if err := services.ValidateRules(rules); err != nil {
+		writeProblem(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, fmt.Sprintf("Invalid rules: %v, available tie-breakers: %s", err, strings.Join(services.TieBreakerNames(), ", ")))
+		return
+	}
+
+	// Ratings are off unless asked for
+	ratingSystem := leagueRequest.RatingSystem
+	if ratingSystem == "" {
+		ratingSystem = services.RatingsNone
+	}
+	if err := services.ValidateRatings(ratingSystem, leagueRequest.SimulateWithRatings); err != nil {
+		writeProblem(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, fmt.Sprintf("Invalid ratings: %v", err))
+		return
+	}
+
+	// Without an explicit seed the league gets a random one, which is stored
+	// so the season can still be replayed
+	seed := time.Now().UnixNano()
+	if leagueRequest.Seed != nil {
+		seed = *leagueRequest.Seed
+	}
+
+	// Resolve the match engine, falling back to the default one
+	engineName := leagueRequest.Engine
+	if engineName == "" {
+		engineName = services.DefaultEngineName
+	}
+	engine, err := services.GetEngine(engineName)
 	if err != nil {
-		http.Error(w, "Failed to get teams from database", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, fmt.Sprintf("%v, available engines: %s", err, strings.Join(services.EngineNames(), ", ")))
 		return
 	}
-	
+
+	// League name and format
+	name := strings.TrimSpace(leagueRequest.Name)
+	if name == "" {
+		name = "New League"
+	}
+
+	legs := leagueRequest.Legs
+	if legs == 0 {
+		legs = services.DoubleRoundRobin
+	}
+
+	// Get the chosen teams, or every team when none are given
+	dbTeams, ok := h.selectTeams(w, r, leagueRequest.TeamIDs)
+	if !ok {
+		return
+	}
+
 	if len(dbTeams) < 2 {
-		http.Error(w, "At least 2 teams required in database", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusConflict, CodeNotEnoughTeams, "At least 2 teams required in database")
 		return
 	}
-	
+
 	// Create in-memory league for simulation first to get actual fixture count
-	h.league = services.NewGenerateLeague(dbTeams)
-	
-	// Create league in database with actual number of weeks from fixtures
-	leagueID, err := h.repo.CreateLeague("New League", len(h.league.Fixtures))
-	if err != nil {
-		http.Error(w, "Failed to create league", http.StatusInternalServerError)
+	league := services.NewSeededLeague(dbTeams, seed)
+	league.Engine = engine
+	league.Rules = rules
+	league.RatingSystem = ratingSystem
+	league.SimulateWithRatings = leagueRequest.SimulateWithRatings
+	league.Fixtures = services.GenerateFixtureWithLegs(dbTeams, legs)
+
+	// Make sure every pair meets the right number of times at each venue
+	if err := services.ValidateSeason(league.Fixtures, dbTeams, legs); err != nil {
+		writeError(w, r, err, "Generated fixtures are invalid")
 		return
 	}
-	
-	// Add teams to league
+
 	var teamIDs []int
 	for _, team := range dbTeams {
 		teamIDs = append(teamIDs, team.ID)
 	}
-	
-	if err := h.repo.AddTeamsToLeague(leagueID, teamIDs); err != nil {
-		http.Error(w, "Failed to add teams to league", http.StatusInternalServerError)
+
+	// Create the league with its teams, stats and fixtures in one step, with
+	// the actual number of weeks from the fixtures
+	leagueID, err := h.repo.CreateLeague(r.Context(), models.League{
+		Name:       name,
+		TotalWeeks: len(league.Fixtures),
+		Seed:       seed,
+		Engine:     engineName,
+		Legs:       legs,
+		Rules:      rules,
+
+		RatingSystem:        ratingSystem,
+		SimulateWithRatings: leagueRequest.SimulateWithRatings,
+	}, teamIDs, league.Fixtures)
+	if err != nil {
+		writeError(w, r, err, "Failed to create league")
+		return
+	}
+
+	// Remember the league for the /api/league aliases
+	h.mu.Lock()
+	if makeDefault || h.defaultLeagueID == 0 {
+		h.defaultLeagueID = leagueID
+		h.defaultVersion++
+	}
+	h.mu.Unlock()
+
+	response := models.LeagueResponse{
+		LeagueID:    leagueID,
+		Name:        name,
+		CurrentWeek: league.CurrentWeek,
+		TotalWeeks:  len(league.Fixtures),
+		Status:      "League created successfully",
+		Seed:        seed,
+		Engine:      engineName,
+		Legs:        legs,
+		TeamIDs:     teamIDs,
+		Rules:       &rules,
+
+		RatingSystem:        ratingSystem,
+		SimulateWithRatings: leagueRequest.SimulateWithRatings,
+	}
+
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// selectTeams loads the teams with the given IDs, or every stored team when
+// the list is empty. It writes the error response itself when it fails.
+func (h *LeagueHandler) selectTeams(w http.ResponseWriter, r *http.Request, teamIDs []int) ([]models.Team, bool) {
+	if len(teamIDs) == 0 {
+		teams, err := h.repo.GetAllTeams(r.Context())
+		if err != nil {
+			writeError(w, r, err, "Failed to get teams from database")
+			return nil, false
+		}
+		return teams, true
+	}
+
+	var teams []models.Team
+	seen := make(map[int]bool)
+	for _, id := range teamIDs {
+		if seen[id] {
+			writeProblem(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, fmt.Sprintf("Team %d is listed more than once", id))
+			return nil, false
+		}
+		seen[id] = true
+
+		team, err := h.repo.GetTeamByID(r.Context(), id)
+		if errors.Is(err, database.ErrNotFound) {
+			writeProblem(w, r, http.StatusUnprocessableEntity, CodeTeamNotFound, fmt.Sprintf("Team %d not found", id))
+			return nil, false
+		}
+		if err != nil {
+			writeError(w, r, err, "Failed to get team")
+			return nil, false
+		}
+		teams = append(teams, *team)
+	}
+
+	return teams, true
+}
+
+// ListLeagues - GET /api/leagues
+func (h *LeagueHandler) ListLeagues(w http.ResponseWriter, r *http.Request) {
+	leagues, err := h.repo.GetLeagues(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to get leagues")
 		return
 	}
-	
-	// Initialize team stats
-	if err := h.repo.InitializeTeamStats(leagueID, teamIDs); err != nil {
-		http.Error(w, "Failed to initialize team stats", http.StatusInternalServerError)
+
+	defaultID, err := h.currentDefaultLeague(r.Context())
+	if err != nil {
+		writeError(w, r, err, "Failed to get default league")
 		return
 	}
-	
-	// Store the league ID
-	h.leagueID = leagueID
-	
-	// Store fixtures in database for consistency
-	if err := h.repo.StoreFixtures(leagueID, h.league.Fixtures); err != nil {
-		http.Error(w, "Failed to store fixtures", http.StatusInternalServerError)
+
+	response := models.LeaguesResponse{
+		Leagues:         leagues,
+		Count:           len(leagues),
+		DefaultLeagueID: defaultID,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// SetDefaultLeague - POST /api/leagues/{id}/default
+// Chooses the league served by the /api/league/* aliases
+func (h *LeagueHandler) SetDefaultLeague(w http.ResponseWriter, r *http.Request) {
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
 		return
 	}
-	
-	response := models.LeagueResponse{
-		CurrentWeek: h.league.CurrentWeek,
-		TotalWeeks:  len(h.league.Fixtures),
-		Status:      "League created successfully",
+
+	h.mu.Lock()
+	h.defaultLeagueID = leagueID
+	h.defaultVersion++
+	h.mu.Unlock()
+
+	response := map[string]interface{}{
+		"status":            "Default league updated successfully",
+		"default_league_id": leagueID,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// resolveLeagueID returns the league addressed by the request: the {id} path
+// value for /api/leagues/{id}/... routes, or the default league for the
+// /api/league/* aliases. It writes the error response itself when it fails.
+func (h *LeagueHandler) resolveLeagueID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		leagueID, err := h.currentDefaultLeague(r.Context())
+		if err != nil {
+			writeError(w, r, err, "Failed to get default league")
+			return 0, false
+		}
+		if leagueID == 0 {
+			writeProblem(w, r, http.StatusNotFound, CodeNoLeague, "No league created yet. Please create a league first.")
+			return 0, false
+		}
+		return leagueID, true
+	}
+
+	leagueID, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid league ID")
+		return 0, false
+	}
+
+	exists, err := h.repo.LeagueExists(r.Context(), leagueID)
+	if err != nil {
+		writeError(w, r, err, "Failed to check league existence")
+		return 0, false
+	}
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, CodeLeagueNotFound, "League not found")
+		return 0, false
+	}
+
+	return leagueID, true
+}
+
+// currentDefaultLeague returns the default league, falling back to the most
+// recently created league after a restart. It returns 0 when there is none.
+// The lock is never held during the query, so a slow lookup does not hold up
+// requests that find the league cached.
+func (h *LeagueHandler) currentDefaultLeague(ctx context.Context) (int, error) {
+	h.mu.Lock()
+	leagueID, version := h.defaultLeagueID, h.defaultVersion
+	h.mu.Unlock()
+	if leagueID != 0 {
+		return leagueID, nil
+	}
+
+	latestID, err := h.repo.GetLatestLeagueID(ctx)
+	if err != nil {
+		return 0, err
 	}
-	
-	// Set CORS headers (after Content-Type to ensure they're not overridden)
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	// Only fill the cache if no league was chosen, created or cleared while
+	// the query ran; otherwise the newer value wins
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.defaultVersion == version {
+		h.defaultLeagueID = latestID
+		return latestID, nil
+	}
+	return h.defaultLeagueID, nil
 }
 
 // PlayWeek - POST /api/league/play-week
 func (h *LeagueHandler) PlayWeek(w http.ResponseWriter, r *http.Request) {
-	if h.leagueID == 0 {
-		http.Error(w, "No league created yet. Please create a league first.", http.StatusBadRequest)
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
 		return
 	}
-	
+
 	// Play one week using the database method
-	_, err := h.repo.PlayWeek(h.leagueID)
+	_, err := h.repo.PlayWeek(r.Context(), leagueID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to play week: %v", err), http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to play week")
 		return
 	}
-	
+
 	// Get updated league status
-	leagueStatus, err := h.repo.GetLeagueStatus(h.leagueID)
+	leagueStatus, err := h.repo.GetLeagueStatus(r.Context(), leagueID)
 	if err != nil {
-		http.Error(w, "Failed to get league status", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to get league status")
 		return
 	}
-	
+
 	response := models.LeagueResponse{
+		LeagueID:    leagueID,
 		CurrentWeek: leagueStatus.CurrentWeek,
 		TotalWeeks:  leagueStatus.TotalWeeks,
 		Status:      fmt.Sprintf("Week %d played successfully", leagueStatus.CurrentWeek),
+		Seed:        leagueStatus.Seed,
+		Engine:      leagueStatus.Engine,
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // PlayAllWeeks - POST /api/league/play-all
 func (h *LeagueHandler) PlayAllWeeks(w http.ResponseWriter, r *http.Request) {
-	if h.leagueID == 0 {
-		http.Error(w, "No league created yet. Please create a league first.", http.StatusBadRequest)
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
 		return
 	}
-	
+
 	// Play all remaining weeks using the stored league ID
-	matches, err := h.repo.PlayAllWeeks(h.leagueID)
+	matches, err := h.repo.PlayAllWeeks(r.Context(), leagueID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to play all weeks: %v", err), http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to play all weeks")
 		return
 	}
-	
+
 	// Group matches by week for better display
 	matchesByWeek := make(map[int][]models.Match)
 	for _, match := range matches {
 		matchesByWeek[match.Week] = append(matchesByWeek[match.Week], match)
 	}
-	
+
 	response := map[string]interface{}{
-		"status":        "All weeks played successfully",
-		"total_matches": len(matches),
+		"status":          "All weeks played successfully",
+		"total_matches":   len(matches),
 		"matches_by_week": matchesByWeek,
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // GetLeagueTable - GET /api/league/table
 func (h *LeagueHandler) GetLeagueTable(w http.ResponseWriter, r *http.Request) {
-	if h.leagueID == 0 {
-		http.Error(w, "No league created yet. Please create a league first.", http.StatusBadRequest)
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
 		return
 	}
-	
+
 	// Get league table from database using the stored league ID
-	standings, err := h.repo.GetLeagueTable(h.leagueID)
+	standings, err := h.repo.GetLeagueTable(r.Context(), leagueID)
 	if err != nil {
-		http.Error(w, "Failed to get league table", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to get league table")
 		return
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(standings)
+
+	form, err := h.formTable(r.Context(), leagueID)
+	if err != nil {
+		writeError(w, r, err, "Failed to get team form")
+		return
+	}
+	for i := range standings {
+		standings[i].Form = form[standings[i].TeamName].Results
+		standings[i].FormRating = roundTo(form[standings[i].TeamName].Rating, 3)
+	}
+
+	writeJSON(w, http.StatusOK, standings)
+}
+
+// GetRatings - GET /api/league/ratings
+// Returns the current Elo rating of every team, highest first, and the
+// rating of every team after each played week
+func (h *LeagueHandler) GetRatings(w http.ResponseWriter, r *http.Request) {
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.repo.GetLeagueStatus(r.Context(), leagueID)
+	if err != nil {
+		writeError(w, r, err, "Failed to get league status")
+		return
+	}
+	if status.RatingSystem != services.RatingsElo {
+		writeProblem(w, r, http.StatusConflict, CodeRatingsDisabled, "League does not use a rating system")
+		return
+	}
+
+	history, err := h.repo.GetRatingHistory(r.Context(), leagueID)
+	if err != nil {
+		writeError(w, r, err, "Failed to get rating history")
+		return
+	}
+
+	// The current ratings are the ones after the latest week
+	latest := 0
+	for i := range history {
+		history[i].Rating = roundTo(history[i].Rating, 1)
+		if history[i].Week > latest {
+			latest = history[i].Week
+		}
+	}
+	ratings := []models.TeamRating{}
+	for _, rating := range history {
+		if rating.Week == latest {
+			ratings = append(ratings, rating)
+		}
+	}
+	sort.SliceStable(ratings, func(i, j int) bool {
+		return ratings[i].Rating > ratings[j].Rating
+	})
+
+	writeJSON(w, http.StatusOK, models.RatingsResponse{
+		LeagueID: leagueID,
+		Ratings:  ratings,
+		History:  history,
+	})
+}
+
+// formTable returns the form of the teams of a league, as the simulator sees
+// it for the next week
+func (h *LeagueHandler) formTable(ctx context.Context, leagueID int) (map[string]services.Form, error) {
+	league, _, err := h.repo.LoadSimulation(ctx, leagueID)
+	if err != nil {
+		return nil, err
+	}
+
+	form := make(map[string]services.Form, len(league.Teams))
+	for _, team := range league.Teams {
+		form[team.Name] = league.Form(team.Name)
+	}
+	return form, nil
+}
+
+// addLeagueForm fills in the form of the teams taking part in the league
+// chosen by the league query parameter, or the default league without one.
+// Teams outside that league, or every team when there is no league, are
+// left without form. It writes the error response itself when it fails.
+func (h *LeagueHandler) addLeagueForm(w http.ResponseWriter, r *http.Request, teams []models.Team) bool {
+	var leagueID int
+	if value := r.URL.Query().Get("league"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid league ID")
+			return false
+		}
+		exists, err := h.repo.LeagueExists(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err, "Failed to check league existence")
+			return false
+		}
+		if !exists {
+			writeProblem(w, r, http.StatusNotFound, CodeLeagueNotFound, "League not found")
+			return false
+		}
+		leagueID = id
+	} else {
+		id, err := h.currentDefaultLeague(r.Context())
+		if err != nil {
+			writeError(w, r, err, "Failed to get default league")
+			return false
+		}
+		leagueID = id
+	}
+	if leagueID == 0 {
+		return true
+	}
+
+	form, err := h.formTable(r.Context(), leagueID)
+	if err != nil {
+		writeError(w, r, err, "Failed to get team form")
+		return false
+	}
+	for i := range teams {
+		if teamForm, exists := form[teams[i].Name]; exists {
+			teams[i].LeagueForm = &models.TeamForm{
+				LeagueID:   leagueID,
+				Form:       teamForm.Results,
+				FormRating: roundTo(teamForm.Rating, 3),
+			}
+		}
+	}
+	return true
 }
 
 // GetMatches - GET /api/league/matches
 func (h *LeagueHandler) GetMatches(w http.ResponseWriter, r *http.Request) {
-	if h.leagueID == 0 {
-		http.Error(w, "No league created yet. Please create a league first.", http.StatusBadRequest)
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
 		return
 	}
-	
+
 	// Get matches from database using the stored league ID
-	matches, err := h.repo.GetMatches(h.leagueID)
+	matches, err := h.repo.GetMatches(r.Context(), leagueID)
 	if err != nil {
-		http.Error(w, "Failed to get matches", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to get matches")
 		return
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(matches)
+
+	writeJSON(w, http.StatusOK, matches)
 }
 
 // GetWeekMatches - GET /api/league/matches/week/{week}
 func (h *LeagueHandler) GetWeekMatches(w http.ResponseWriter, r *http.Request) {
-	// Extract week number from URL path: /api/league/matches/week/1
-	path := strings.TrimPrefix(r.URL.Path, "/api/league/matches/week/")
-	weekStr := strings.Split(path, "/")[0]
-	
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
+		return
+	}
+
+	// Week number comes from the {week} path value
+	weekStr := r.PathValue("week")
+
 	if weekStr == "" {
-		http.Error(w, "Week number required", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Week number required")
 		return
 	}
-	
+
 	week, err := strconv.Atoi(weekStr)
 	if err != nil {
-		http.Error(w, "Invalid week number", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid week number")
 		return
 	}
-	
+
 	if week < 1 {
-		http.Error(w, "Week number must be positive", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Week number must be positive")
 		return
 	}
-	
+
 	// Get matches for the specific week from database using the stored league ID
-	weekMatches, err := h.repo.GetMatchesByWeek(h.leagueID, week)
+	weekMatches, err := h.repo.GetMatchesByWeek(r.Context(), leagueID, week)
 	if err != nil {
-		http.Error(w, "Failed to get matches for week", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to get matches for week")
 		return
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(weekMatches)
+
+	writeJSON(w, http.StatusOK, weekMatches)
+}
+
+// UpdateMatch - PUT /api/league/matches/{matchID}
+// Sets or corrects the score of a match and recalculates the league table
+func (h *LeagueHandler) UpdateMatch(w http.ResponseWriter, r *http.Request) {
+	leagueID, matchID, ok := h.resolveMatchID(w, r)
+	if !ok {
+		return
+	}
+
+	var matchRequest models.UpdateMatchRequest
+
+	if !decodeRequest(w, r, &matchRequest, false) {
+		return
+	}
+
+	var cards models.MatchCards
+	if matchRequest.Cards != nil {
+		cards = *matchRequest.Cards
+	}
+	if err := h.repo.SetMatchResult(r.Context(), leagueID, matchID, *matchRequest.HomeScore, *matchRequest.AwayScore, cards); err != nil {
+		writeError(w, r, err, "Failed to update match")
+		return
+	}
+
+	match, err := h.repo.GetMatch(r.Context(), leagueID, matchID)
+	if err != nil {
+		writeError(w, r, err, "Failed to get match")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, match)
+}
+
+// DeleteMatchResult - DELETE /api/league/matches/{matchID}
+// Un-plays a match and recalculates the league table and ratings from its
+// week on; a match of a played week is played again with the next week
+func (h *LeagueHandler) DeleteMatchResult(w http.ResponseWriter, r *http.Request) {
+	leagueID, matchID, ok := h.resolveMatchID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.UnplayMatch(r.Context(), leagueID, matchID); err != nil {
+		writeError(w, r, err, "Failed to un-play match")
+		return
+	}
+
+	response := map[string]interface{}{
+		"status":  "Match result removed successfully",
+		"message": fmt.Sprintf("Match with ID %d is no longer played", matchID),
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// RewindLeague - POST /api/league/rewind?week=N
+// Un-plays every match after week N and moves the league back to that week
+func (h *LeagueHandler) RewindLeague(w http.ResponseWriter, r *http.Request) {
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
+		return
+	}
+
+	weekStr := r.URL.Query().Get("week")
+	if weekStr == "" {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Week number required")
+		return
+	}
+
+	week, err := strconv.Atoi(weekStr)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid week number")
+		return
+	}
+
+	leagueStatus, err := h.repo.GetLeagueStatus(r.Context(), leagueID)
+	if err != nil {
+		writeError(w, r, err, "Failed to get league status")
+		return
+	}
+
+	if week < 0 || week > leagueStatus.CurrentWeek {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Week must be between 0 and the current week (%d)", leagueStatus.CurrentWeek))
+		return
+	}
+
+	if err := h.repo.RewindLeague(r.Context(), leagueID, week); err != nil {
+		writeError(w, r, err, "Failed to rewind league")
+		return
+	}
+
+	response := models.LeagueResponse{
+		LeagueID:    leagueID,
+		CurrentWeek: week,
+		TotalWeeks:  leagueStatus.TotalWeeks,
+		Status:      fmt.Sprintf("League rewound to week %d", week),
+		Seed:        leagueStatus.Seed,
+		Engine:      leagueStatus.Engine,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// resolveMatchID returns the league and the match addressed by the request,
+// making sure the match belongs to the league. It writes the error response
+// itself when it fails.
+func (h *LeagueHandler) resolveMatchID(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+
+	matchID, err := strconv.Atoi(r.PathValue("matchID"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid match ID")
+		return 0, 0, false
+	}
+
+	exists, err := h.repo.MatchExists(r.Context(), leagueID, matchID)
+	if err != nil {
+		writeError(w, r, err, "Failed to check match existence")
+		return 0, 0, false
+	}
+	if !exists {
+		writeProblem(w, r, http.StatusNotFound, CodeMatchNotFound, "Match not found")
+		return 0, 0, false
+	}
+
+	return leagueID, matchID, true
 }
 
 // GetLeagueStatus - GET /api/league/status
 func (h *LeagueHandler) GetLeagueStatus(w http.ResponseWriter, r *http.Request) {
-	if h.league == nil {
-		http.Error(w, "League not initialized", http.StatusBadRequest)
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
 		return
 	}
-	
-	status := "In Progress"
-	if h.league.CurrentWeek >= len(h.league.Fixtures) {
-		status = "Season Complete"
+
+	response, err := h.repo.GetLeagueStatus(r.Context(), leagueID)
+	if err != nil {
+		writeError(w, r, err, "Failed to get league status")
+		return
 	}
-	
-	response := models.LeagueResponse{
-		CurrentWeek: h.league.CurrentWeek,
-		TotalWeeks:  len(h.league.Fixtures),
-		Status:      status,
+
+	response.LeagueID = leagueID
+	response.Status = "In Progress"
+	if response.CurrentWeek >= response.TotalWeeks {
+		response.Status = "Season Complete"
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // ClearLeague - DELETE /api/league
 func (h *LeagueHandler) ClearLeague(w http.ResponseWriter, r *http.Request) {
-	if h.leagueID == 0 {
-		http.Error(w, "No league created yet. Please create a league first.", http.StatusBadRequest)
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
 		return
 	}
-	
+
 	// Clear league data from database using the stored league ID
-	if err := h.repo.ClearLeague(h.leagueID); err != nil {
-		http.Error(w, "Failed to clear league: "+err.Error(), http.StatusInternalServerError)
+	if err := h.repo.ClearLeague(r.Context(), leagueID); err != nil {
+		writeError(w, r, err, "Failed to clear league")
 		return
 	}
-	
-	// Reset in-memory league and league ID
-	h.league = nil
-	h.leagueID = 0
-	
+
+	// Forget the default league if it was the one removed
+	h.mu.Lock()
+	if h.defaultLeagueID == leagueID {
+		h.defaultLeagueID = 0
+	}
+	h.defaultVersion++
+	h.mu.Unlock()
+
 	response := models.LeagueResponse{
 		CurrentWeek: 0,
 		TotalWeeks:  0,
 		Status:      "League cleared successfully",
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // InitializeDatabase - POST /api/init-db
 func (h *LeagueHandler) InitializeDatabase(w http.ResponseWriter, r *http.Request) {
-	if err := h.repo.InitializeDatabase(); err != nil {
-		http.Error(w, "Failed to initialize database: "+err.Error(), http.StatusInternalServerError)
+	if err := h.repo.ResetDatabase(r.Context()); err != nil {
+		writeError(w, r, err, "Failed to initialize database")
 		return
 	}
-	
+
 	response := map[string]interface{}{
 		"status":  "Database initialized successfully",
 		"message": "Default teams have been added to the database",
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // ClearTeams - DELETE /api/clear-teams
 func (h *LeagueHandler) ClearTeams(w http.ResponseWriter, r *http.Request) {
 	// Clear all teams from database
-	if err := h.repo.ClearTeams(); err != nil {
-		http.Error(w, "Failed to clear teams: "+err.Error(), http.StatusInternalServerError)
+	if err := h.repo.ClearTeams(r.Context()); err != nil {
+		writeError(w, r, err, "Failed to clear teams")
 		return
 	}
-	
+
 	response := map[string]interface{}{
 		"status":  "Teams cleared successfully",
 		"message": "All teams have been removed from the database",
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, response)
 }
 
-// GetTeams - GET /api/teams
+// GetTeams - GET /api/teams?league=1
+// Teams of the league, or of the default league without one, carry their form
 func (h *LeagueHandler) GetTeams(w http.ResponseWriter, r *http.Request) {
-	teams, err := h.repo.GetAllTeams()
+	teams, err := h.repo.GetAllTeams(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to get teams", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to get teams")
 		return
 	}
-	
+
+	if !h.addLeagueForm(w, r, teams) {
+		return
+	}
+
 	// Get team count
-	count, err := h.repo.GetTeamCount()
+	count, err := h.repo.GetTeamCount(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to get team count", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to get team count")
 		return
 	}
-	
+
 	response := models.TeamsResponse{
 		Teams:   teams,
 		Count:   count,
 		Message: "Teams retrieved successfully",
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // AddTeam - POST /api/teams
 func (h *LeagueHandler) AddTeam(w http.ResponseWriter, r *http.Request) {
 	var teamRequest models.AddTeamRequest
-	
-	if err := json.NewDecoder(r.Body).Decode(&teamRequest); err != nil {
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
-		return
-	}
-	
-	// Validate team name
-	if teamRequest.Name == "" {
-		http.Error(w, "Team name is required", http.StatusBadRequest)
-		return
-	}
-	
-	// Check name length
-	if len(teamRequest.Name) > 50 {
-		http.Error(w, "Team name must be 50 characters or less", http.StatusBadRequest)
-		return
-	}
-	
-	// Validate strength
-	if teamRequest.Strength < 1 || teamRequest.Strength > 100 {
-		http.Error(w, "Strength must be between 1 and 100", http.StatusBadRequest)
+
+	if !decodeRequest(w, r, &teamRequest, false) {
 		return
 	}
-	
+
 	// Check if team already exists
-	exists, err := h.repo.TeamExists(teamRequest.Name)
+	exists, err := h.repo.TeamExists(r.Context(), teamRequest.Name)
 	if err != nil {
-		http.Error(w, "Failed to check team existence", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to check team existence")
 		return
 	}
-	
+
 	if exists {
-		http.Error(w, "Team with this name already exists", http.StatusConflict)
+		writeProblem(w, r, http.StatusConflict, CodeTeamExists, "Team with this name already exists")
 		return
 	}
-	
+
 	// Add team and get the created team with ID
-	team, err := h.repo.AddTeamWithID(teamRequest.Name, teamRequest.Strength)
+	team, err := h.repo.AddTeamWithID(r.Context(), models.Team{
+		Name:     teamRequest.Name,
+		Strength: teamRequest.Strength,
+		Attack:   teamRequest.Attack,
+		Defence:  teamRequest.Defence,
+	})
 	if err != nil {
-		http.Error(w, "Failed to add team: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to add team")
 		return
 	}
-	
+
 	response := models.TeamResponse{
 		ID:       team.ID,
 		Name:     team.Name,
 		Strength: team.Strength,
+		Attack:   team.Attack,
+		Defence:  team.Defence,
 		Message:  "Team added successfully",
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// teamID reads the {id} path value of the /api/teams/{id} routes
+func teamID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid team ID")
+		return 0, false
+	}
+	return id, true
 }
 
-// GetTeam - GET /api/teams/{id}
+// GetTeam - GET /api/teams/{id}?league=1
+// A team playing in the league, or in the default league without one, carries its form
 func (h *LeagueHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
-	// Extract team ID from URL path: /api/teams/1
-	path := strings.TrimPrefix(r.URL.Path, "/api/teams")
-	// Remove leading slash if present
-	path = strings.TrimPrefix(path, "/")
-	idStr := strings.Split(path, "/")[0]
-	
-	if idStr == "" {
-		http.Error(w, "Team ID required", http.StatusBadRequest)
+	id, ok := teamID(w, r)
+	if !ok {
 		return
 	}
-	
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+
+	team, err := h.repo.GetTeamByID(r.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, CodeTeamNotFound, "Team not found")
 		return
 	}
-	
-	team, err := h.repo.GetTeamByID(id)
 	if err != nil {
-		http.Error(w, "Team not found", http.StatusNotFound)
+		writeError(w, r, err, "Failed to get team")
+		return
+	}
+
+	teams := []models.Team{*team}
+	if !h.addLeagueForm(w, r, teams) {
 		return
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(team)
+
+	writeJSON(w, http.StatusOK, teams[0])
 }
 
 // UpdateTeam - PUT /api/teams/{id}
 func (h *LeagueHandler) UpdateTeam(w http.ResponseWriter, r *http.Request) {
-	// Extract team ID from URL path: /api/teams/1
-	path := strings.TrimPrefix(r.URL.Path, "/api/teams")
-	// Remove leading slash if present
-	path = strings.TrimPrefix(path, "/")
-	idStr := strings.Split(path, "/")[0]
-	
-	if idStr == "" {
-		http.Error(w, "Team ID required", http.StatusBadRequest)
-		return
-	}
-	
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+	id, ok := teamID(w, r)
+	if !ok {
 		return
 	}
-	
+
 	var teamRequest models.UpdateTeamRequest
-	
-	if err := json.NewDecoder(r.Body).Decode(&teamRequest); err != nil {
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
-		return
-	}
-	
-	// Validate team name
-	if teamRequest.Name == "" {
-		http.Error(w, "Team name is required", http.StatusBadRequest)
-		return
-	}
-	
-	// Check name length
-	if len(teamRequest.Name) > 50 {
-		http.Error(w, "Team name must be 50 characters or less", http.StatusBadRequest)
+
+	if !decodeRequest(w, r, &teamRequest, false) {
 		return
 	}
-	
-	// Validate strength
-	if teamRequest.Strength < 1 || teamRequest.Strength > 100 {
-		http.Error(w, "Strength must be between 1 and 100", http.StatusBadRequest)
+
+	// Check if team exists
+	existingTeam, err := h.repo.GetTeamByID(r.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, CodeTeamNotFound, "Team not found")
 		return
 	}
-	
-	// Check if team exists
-	existingTeam, err := h.repo.GetTeamByID(id)
 	if err != nil {
-		http.Error(w, "Team not found", http.StatusNotFound)
+		writeError(w, r, err, "Failed to get team")
 		return
 	}
-	
+
 	// Check if new name conflicts with another team (if name is being changed)
 	if existingTeam.Name != teamRequest.Name {
-		exists, err := h.repo.TeamExists(teamRequest.Name)
+		exists, err := h.repo.TeamExists(r.Context(), teamRequest.Name)
 		if err != nil {
-			http.Error(w, "Failed to check team existence", http.StatusInternalServerError)
+			writeError(w, r, err, "Failed to check team existence")
 			return
 		}
-		
+
 		if exists {
-			http.Error(w, "Team with this name already exists", http.StatusConflict)
+			writeProblem(w, r, http.StatusConflict, CodeTeamExists, "Team with this name already exists")
 			return
 		}
 	}
-	
+
 	// Update team
-	err = h.repo.UpdateTeam(id, teamRequest.Name, teamRequest.Strength)
+	err = h.repo.UpdateTeam(r.Context(), models.Team{
+		ID:       id,
+		Name:     teamRequest.Name,
+		Strength: teamRequest.Strength,
+		Attack:   teamRequest.Attack,
+		Defence:  teamRequest.Defence,
+	})
 	if err != nil {
-		http.Error(w, "Failed to update team: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to update team")
 		return
 	}
-	
+
 	response := models.TeamResponse{
 		ID:       id,
 		Name:     teamRequest.Name,
 		Strength: teamRequest.Strength,
+		Attack:   teamRequest.Attack,
+		Defence:  teamRequest.Defence,
 		Message:  "Team updated successfully",
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // DeleteTeam - DELETE /api/teams/{id}
 func (h *LeagueHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
-	// Extract team ID from URL path: /api/teams/1
-	path := strings.TrimPrefix(r.URL.Path, "/api/teams")
-	// Remove leading slash if present
-	path = strings.TrimPrefix(path, "/")
-	idStr := strings.Split(path, "/")[0]
-	
-	if idStr == "" {
-		http.Error(w, "Team ID required", http.StatusBadRequest)
+	id, ok := teamID(w, r)
+	if !ok {
 		return
 	}
-	
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+
+	// Check if team exists
+	_, err := h.repo.GetTeamByID(r.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, CodeTeamNotFound, "Team not found")
 		return
 	}
-	
-	// Check if team exists
-	_, err = h.repo.GetTeamByID(id)
 	if err != nil {
-		http.Error(w, "Team not found", http.StatusNotFound)
+		writeError(w, r, err, "Failed to get team")
 		return
 	}
-	
+
 	// Delete team
-	err = h.repo.DeleteTeam(id)
+	err = h.repo.DeleteTeam(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to delete team: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to delete team")
 		return
 	}
-	
+
 	response := map[string]interface{}{
 		"status":  "Team deleted successfully",
 		"message": fmt.Sprintf("Team with ID %d has been deleted", id),
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
 
+	writeJSON(w, http.StatusOK, response)
+}
 
 // GetMatchSchedule - GET /api/league/schedule
 func (h *LeagueHandler) GetMatchSchedule(w http.ResponseWriter, r *http.Request) {
-	if h.leagueID == 0 {
-		http.Error(w, "No league created yet. Please create a league first.", http.StatusBadRequest)
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
 		return
 	}
-	
+
 	// Get match schedule from database using the stored league ID
-	schedule, err := h.repo.GetMatchSchedule(h.leagueID)
+	schedule, err := h.repo.GetMatchSchedule(r.Context(), leagueID)
 	if err != nil {
-		http.Error(w, "Failed to get match schedule", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to get match schedule")
 		return
 	}
-	
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(schedule)
+
+	writeJSON(w, http.StatusOK, schedule)
 }
 
-// GetChampionshipPredictions calculates and returns championship predictions
-// GET /api/league/predictions
+// GetEngines - GET /api/engines
+func (h *LeagueHandler) GetEngines(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"engines": services.EngineNames(),
+		"default": services.DefaultEngineName,
+	}
 
+	writeJSON(w, http.StatusOK, response)
+}
+
+// GetChampionshipPredictions - GET /api/league/predictions?runs=10000&top=2&bottom=1
+// Simulates the remaining fixtures many times and reports title, top-N and
+// bottom-N probabilities together with the expected final points per team
 func (h *LeagueHandler) GetChampionshipPredictions(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-	
-	// Get current league table
-	standings, err := h.repo.GetLeagueTable(h.leagueID)
+
+	leagueID, ok := h.resolveLeagueID(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := queryInt(r, "runs", defaultPredictionRuns, 1, maxPredictionRuns)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	topN, err := queryInt(r, "top", defaultPredictionTopN, 1, maxPredictionPositions)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	bottomN, err := queryInt(r, "bottom", defaultPredictionBottomN, 1, maxPredictionPositions)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get league table: %v", err), http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
 		return
 	}
-	
-	// Calculate championship predictions
-	predictions := h.calculateChampionshipPredictions(standings)
-	
-	// Return predictions as JSON
-	if err := json.NewEncoder(w).Encode(predictions); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to encode predictions: %v", err), http.StatusInternalServerError)
+
+	// Restore the league the way playing a week would, in one consistent read
+	league, remaining, err := h.repo.LoadSimulation(r.Context(), leagueID)
+	if err != nil {
+		writeError(w, r, err, "Failed to load league state")
 		return
 	}
+
+	if topN > len(league.Teams) {
+		topN = len(league.Teams)
+	}
+	if bottomN > len(league.Teams) {
+		bottomN = len(league.Teams)
+	}
+
+	// Run the Monte Carlo simulation, with no more runs than the match
+	// budget allows for the fixtures left
+	runs = services.PredictionRuns(runs, remaining)
+
+	// The simulation stops when the client goes away, the server shuts down
+	// or the response could no longer be written in time
+	ctx := r.Context()
+	if h.simulationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.simulationTimeout)
+		defer cancel()
+	}
+	results, err := services.PredictSeason(ctx, league, remaining, runs, topN, bottomN)
+	if err != nil {
+		writeError(w, r, err, "Failed to simulate the season")
+		return
+	}
+
+	response := PredictionsResponse{
+		Simulations: runs,
+		TopN:        topN,
+		BottomN:     bottomN,
+		Predictions: []ChampionshipPrediction{},
+	}
+
+	for i, result := range results {
+		stats := league.TeamStats[result.TeamName]
+		response.Predictions = append(response.Predictions, ChampionshipPrediction{
+			TeamName:         result.TeamName,
+			Percentage:       roundTo(result.ChampionProbability, 1),
+			TopPercentage:    roundTo(result.TopProbability, 1),
+			BottomPercentage: roundTo(result.BottomProbability, 1),
+			ExpectedPoints:   roundTo(result.ExpectedPoints, 2),
+			Points:           stats.Points,
+			Position:         i + 1,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+const (
+	defaultPredictionRuns    = 10000
+	maxPredictionRuns        = 20000
+	defaultPredictionTopN    = 2
+	defaultPredictionBottomN = 1
+	maxPredictionPositions   = 100
+)
+
+// PredictionsResponse wraps the championship predictions with the simulation settings used
+type PredictionsResponse struct {
+	Simulations int                      `json:"simulations"`
+	TopN        int                      `json:"top_n"`
+	BottomN     int                      `json:"bottom_n"`
+	Predictions []ChampionshipPrediction `json:"predictions"`
 }
 
 // ChampionshipPrediction represents a team's championship probability
 type ChampionshipPrediction struct {
-	TeamName   string  `json:"team_name"`
-	Percentage float64 `json:"percentage"`
-	Points     int     `json:"points"`
-	Position   int     `json:"position"`
-}
-
-// calculateChampionshipPredictions calculates championship percentages based on current points
-func (h *LeagueHandler) calculateChampionshipPredictions(standings []models.TeamStats) []ChampionshipPrediction {
-	var predictions []ChampionshipPrediction
-	
-	if len(standings) == 0 {
-		return predictions
-	}
-	
-	// Calculate total points across all teams
-	totalPoints := 0
-	for _, team := range standings {
-		totalPoints += team.Points
-	}
-	
-	// If no games played yet, give equal percentages
-	if totalPoints == 0 {
-		equalPercentage := 100.0 / float64(len(standings))
-		for i, team := range standings {
-			predictions = append(predictions, ChampionshipPrediction{
-				TeamName:   team.TeamName,
-				Percentage: equalPercentage,
-				Points:     team.Points,
-				Position:   i + 1,
-			})
-		}
-		return predictions
-	}
-	
-	// Group teams by points to handle ties properly
-	teamsByPoints := make(map[int][]models.TeamStats)
-	for _, team := range standings {
-		teamsByPoints[team.Points] = append(teamsByPoints[team.Points], team)
-	}
-	
-	// Sort points in descending order
-	var sortedPoints []int
-	for points := range teamsByPoints {
-		sortedPoints = append(sortedPoints, points)
-	}
-	sort.Sort(sort.Reverse(sort.IntSlice(sortedPoints)))
-	
-	// Calculate predictions with tie handling
-	position := 1
-	for _, points := range sortedPoints {
-		teamsWithSamePoints := teamsByPoints[points]
-		
-		// If multiple teams have same points, calculate equal share for their positions
-		teamsCount := len(teamsWithSamePoints)
-		positionRange := teamsCount
-		
-		// Calculate base percentage for this group of teams
-		basePercentage := (float64(points) / float64(totalPoints)) * 100.0
-		
-		// Distribute percentage equally among tied teams
-		percentagePerTeam := basePercentage / float64(teamsCount)
-		
-		for i, team := range teamsWithSamePoints {
-			// Apply position-based adjustments
-			positionMultiplier := 1.0
-			currentPosition := position + i
-			
-			if currentPosition == 1 {
-				positionMultiplier = 1.3 // Leader gets 30% bonus
-			} else if currentPosition == 2 {
-				positionMultiplier = 1.1 // Second place gets 10% bonus
-			} else if currentPosition >= len(standings)-1 {
-				positionMultiplier = 0.7 // Bottom teams get 30% penalty
-			}
-			
-			finalPercentage := percentagePerTeam * positionMultiplier
-			
-			predictions = append(predictions, ChampionshipPrediction{
-				TeamName:   team.TeamName,
-				Percentage: finalPercentage,
-				Points:     team.Points,
-				Position:   currentPosition,
-			})
-		}
-		
-		position += positionRange
-	}
-	
-	// Normalize percentages to sum to 100%
-	totalPercentage := 0.0
-	for _, pred := range predictions {
-		totalPercentage += pred.Percentage
-	}
-	
-	if totalPercentage > 0 {
-		for i := range predictions {
-			predictions[i].Percentage = (predictions[i].Percentage / totalPercentage) * 100.0
-			// Round to 1 decimal place
-			predictions[i].Percentage = float64(int(predictions[i].Percentage*10)) / 10.0
-		}
+	TeamName         string  `json:"team_name"`
+	Percentage       float64 `json:"percentage"`
+	TopPercentage    float64 `json:"top_n_percentage"`
+	BottomPercentage float64 `json:"bottom_n_percentage"`
+	ExpectedPoints   float64 `json:"expected_points"`
+	Points           int     `json:"points"`
+	Position         int     `json:"position"`
+}
+
+// queryInt reads an optional integer query parameter and checks its range
+func queryInt(r *http.Request, name string, defaultValue, min, max int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s parameter", name)
 	}
-	
-	return predictions
+
+	if value < min || value > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", name, min, max)
+	}
+
+	return value, nil
+}
+
+// roundTo rounds a value to the given number of decimal places
+func roundTo(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
 }
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"insider-league/Models"
+	"insider-league/validation"
+)
+
+// maxBodyBytes caps the size of a request body
+const maxBodyBytes = 1 << 20
+
+// decodeRequest reads the JSON body into v and checks v against its
+// validate tags. Decoding is strict: unknown fields, trailing data and
+// bodies over maxBodyBytes are rejected. An empty body is accepted when
+// optional is set, leaving v as it is. It writes the error response itself
+// when it fails.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}, optional bool) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == io.EOF && optional {
+		err = nil
+	} else if err == nil {
+		// Nothing but whitespace may follow the object
+		if _, extra := decoder.Token(); extra != io.EOF {
+			err = errors.New("body must contain a single JSON object")
+		}
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, CodeBodyTooLarge,
+			fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit))
+		return false
+	case err == io.EOF:
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidJSON, "Request body is required")
+		return false
+	case err != nil:
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON format: "+err.Error())
+		return false
+	}
+
+	if err := validation.Struct(v); err != nil {
+		problem := models.NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+		problem.Errors, _ = err.(validation.Errors)
+		sendProblem(w, r, problem)
+		return false
+	}
+
+	return true
+}
@@ -1,59 +1,217 @@
 package models
 
+import (
+	"net/http"
+
+	"insider-league/validation"
+)
+
 type Team struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
 	Strength int    `json:"strength"`
+
+	// Attack and Defence optionally split the strength, 1-100 each; a team
+	// without them uses its strength for both
+	Attack  *int `json:"attack,omitempty"`
+	Defence *int `json:"defence,omitempty"`
+
+	// LeagueForm is only filled in by the team endpoints
+	LeagueForm *TeamForm `json:"league_form,omitempty"`
+}
+
+// TeamForm is a team's recent results in one league, oldest first, and the
+// form rating the simulator derives from them, between -1 and 1
+type TeamForm struct {
+	LeagueID   int     `json:"league_id"`
+	Form       string  `json:"form"`
+	FormRating float64 `json:"form_rating"`
 }
 
 type Match struct {
-	Week      int    `json:"week"`
-	HomeTeam  string `json:"home_team"`
-	AwayTeam  string `json:"away_team"`
-	HomeScore int    `json:"home_score"`
-	AwayScore int    `json:"away_score"`
+	ID        int        `json:"id,omitempty"`
+	Week      int        `json:"week"`
+	HomeTeam  string     `json:"home_team"`
+	AwayTeam  string     `json:"away_team"`
+	HomeScore int        `json:"home_score"`
+	AwayScore int        `json:"away_score"`
+	Cards     MatchCards `json:"cards"`
+	Status    string     `json:"status,omitempty"`
+}
+
+// MatchCards is the disciplinary record of both teams in a match; the fair
+// play tie-breaker adds it up
+type MatchCards struct {
+	HomeYellow int `json:"home_yellow" validate:"min=0,max=20"`
+	HomeRed    int `json:"home_red" validate:"min=0,max=5"`
+	AwayYellow int `json:"away_yellow" validate:"min=0,max=20"`
+	AwayRed    int `json:"away_red" validate:"min=0,max=5"`
 }
 
+// Match statuses; a fixture row moves from scheduled to played and back
+const (
+	MatchScheduled = "scheduled"
+	MatchPlayed    = "played"
+)
+
 type TeamStats struct {
-	TeamName     string `json:"team_name"`
-	Played       int    `json:"played"`
-	Won          int    `json:"won"`
-	Drawn        int    `json:"drawn"`
-	Lost         int    `json:"lost"`
-	GoalsFor     int    `json:"goals_for"`
-	GoalsAgainst int    `json:"goals_against"`
-	Points       int    `json:"points"`
-	GoalDiff     int    `json:"goal_difference"`
-	Position     int    `json:"position"`
+	TeamName     string  `json:"team_name"`
+	Played       int     `json:"played"`
+	Won          int     `json:"won"`
+	Drawn        int     `json:"drawn"`
+	Lost         int     `json:"lost"`
+	GoalsFor     int     `json:"goals_for"`
+	GoalsAgainst int     `json:"goals_against"`
+	Points       int     `json:"points"`
+	GoalDiff     int     `json:"goal_difference"`
+	Position     int     `json:"position"`
+	Form         string  `json:"form"`
+	FormRating   float64 `json:"form_rating"`
 }
 
 type LeagueResponse struct {
-	CurrentWeek int    `json:"current_week"`
-	TotalWeeks  int    `json:"total_weeks"`
-	Status      string `json:"status"`
-	Progress    string `json:"progress"`
+	LeagueID            int          `json:"league_id,omitempty"`
+	Name                string       `json:"name,omitempty"`
+	CurrentWeek         int          `json:"current_week"`
+	TotalWeeks          int          `json:"total_weeks"`
+	Status              string       `json:"status"`
+	Progress            string       `json:"progress"`
+	Seed                int64        `json:"seed"`
+	Engine              string       `json:"engine,omitempty"`
+	Legs                int          `json:"legs,omitempty"`
+	TeamIDs             []int        `json:"team_ids,omitempty"`
+	Rules               *LeagueRules `json:"rules,omitempty"`
+	RatingSystem        string       `json:"rating_system,omitempty"`
+	SimulateWithRatings bool         `json:"simulate_with_ratings"`
+}
+
+// League describes a stored league
+type League struct {
+	ID                  int         `json:"id"`
+	Name                string      `json:"name"`
+	CurrentWeek         int         `json:"current_week"`
+	TotalWeeks          int         `json:"total_weeks"`
+	Status              string      `json:"status"`
+	Seed                int64       `json:"seed"`
+	Engine              string      `json:"engine"`
+	Legs                int         `json:"legs"`
+	Rules               LeagueRules `json:"rules"`
+	RatingSystem        string      `json:"rating_system"`
+	SimulateWithRatings bool        `json:"simulate_with_ratings"`
+}
+
+// TeamRating is a team's Elo rating after a week of a league; week 0 holds
+// the rating the team started with
+type TeamRating struct {
+	TeamName string  `json:"team_name"`
+	Week     int     `json:"week"`
+	Rating   float64 `json:"rating"`
+}
+
+// RatingsResponse is the body of GET /api/league/ratings: the current rating
+// of every team, highest first, and the rating of every team after each week
+type RatingsResponse struct {
+	LeagueID int          `json:"league_id"`
+	Ratings  []TeamRating `json:"ratings"`
+	History  []TeamRating `json:"history"`
+}
+
+// LeagueRules is the scoring profile of a league: the points given for each
+// result, an optional bonus for high-scoring matches, the tie-breakers that
+// order teams level on points, applied in turn, and whether a head-to-head
+// mini-league comes before them
+type LeagueRules struct {
+	PointsWin     int      `json:"points_win" validate:"min=0,max=10"`
+	PointsDraw    int      `json:"points_draw" validate:"min=0,max=10"`
+	PointsLoss    int      `json:"points_loss" validate:"min=0,max=10"`
+	BonusPoints   int      `json:"bonus_points" validate:"min=0,max=10"`
+	BonusGoals    int      `json:"bonus_goals" validate:"min=0,max=20"`
+	TieBreakers   []string `json:"tie_breakers" validate:"max=10"`
+	StandingsMode string   `json:"standings_mode" validate:"oneof=overall head_to_head"`
+}
+
+type LeaguesResponse struct {
+	Leagues         []League `json:"leagues"`
+	Count           int      `json:"count"`
+	DefaultLeagueID int      `json:"default_league_id"`
+}
+
+// CreateLeagueRequest is the optional body of POST /api/league.
+// An empty team list uses every stored team, and at most 40 teams can be
+// listed; legs is 1, 2 or 4. Rules left out keep the default rules.
+// rating_system "elo" tracks Elo ratings, and simulate_with_ratings plays the
+// matches with them.
+type CreateLeagueRequest struct {
+	Name                string       `json:"name" validate:"max=100"`
+	TeamIDs             []int        `json:"team_ids" validate:"max=40"`
+	Legs                int          `json:"legs" validate:"omitempty,oneof=1 2 4"`
+	Seed                *int64       `json:"seed,omitempty"`
+	Engine              string       `json:"engine,omitempty"`
+	Rules               *LeagueRules `json:"rules,omitempty"`
+	RatingSystem        string       `json:"rating_system" validate:"omitempty,oneof=none elo"`
+	SimulateWithRatings bool         `json:"simulate_with_ratings"`
 }
 
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
+// Problem is the RFC 7807 body returned with every error response. Code is
+// a stable machine-readable identifier; Detail is meant for people.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Code      string `json:"code"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Errors lists the offending fields of a request that failed validation
+	Errors validation.Errors `json:"errors,omitempty"`
+}
+
+// ProblemTypePrefix starts the type URI of every problem, followed by its code
+const ProblemTypePrefix = "urn:insider-league:problem:"
+
+// NewProblem builds a problem for the given status and code
+func NewProblem(status int, code, detail string) Problem {
+	return Problem{
+		Type:   ProblemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// UpdateMatchRequest sets or corrects the score of a match and, optionally,
+// the cards shown in it; a result without cards records none
+type UpdateMatchRequest struct {
+	HomeScore *int        `json:"home_score" validate:"required,min=0,max=99"`
+	AwayScore *int        `json:"away_score" validate:"required,min=0,max=99"`
+	Cards     *MatchCards `json:"cards"`
 }
 
 // New request/response models for team management
 type AddTeamRequest struct {
 	Name     string `json:"name" validate:"required,min=1,max=50"`
 	Strength int    `json:"strength" validate:"required,min=1,max=100"`
+	Attack   *int   `json:"attack" validate:"omitempty,min=1,max=100"`
+	Defence  *int   `json:"defence" validate:"omitempty,min=1,max=100"`
 }
 
+// UpdateTeamRequest replaces every field of a team; leaving out attack or
+// defence clears it
 type UpdateTeamRequest struct {
 	Name     string `json:"name" validate:"required,min=1,max=50"`
 	Strength int    `json:"strength" validate:"required,min=1,max=100"`
+	Attack   *int   `json:"attack" validate:"omitempty,min=1,max=100"`
+	Defence  *int   `json:"defence" validate:"omitempty,min=1,max=100"`
 }
 
 type TeamResponse struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
 	Strength int    `json:"strength"`
+	Attack   *int   `json:"attack,omitempty"`
+	Defence  *int   `json:"defence,omitempty"`
 	Message  string `json:"message,omitempty"`
 }
 
@@ -62,4 +220,3 @@ type TeamsResponse struct {
 	Count   int    `json:"count"`
 	Message string `json:"message,omitempty"`
 }
-
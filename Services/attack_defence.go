@@ -0,0 +1,65 @@
+package services
+
+import (
+	"insider-league/Models"
+	"math"
+)
+
+// AttackDefenceModel works out each side's expected goals from its attack
+// against the other team's defence, so a free-scoring side with a leaky
+// defence and a dull, solid one of the same strength produce different
+// scorelines. Teams without attack or defence ratings use their strength for
+// the missing one. Goals are sampled like the Poisson engine does.
+type AttackDefenceModel struct {
+	Goals        PoissonModel // base goals, low-score correction and score limit
+	RatingWeight float64      // how strongly the attack to defence ratio moves expected goals
+}
+
+// DefaultAttackDefenceModel is the engine registered as "attack_defence"
+var DefaultAttackDefenceModel = AttackDefenceModel{
+	Goals:        DefaultPoissonModel,
+	RatingWeight: 1.0,
+}
+
+// Name returns the registry name of the attack and defence engine
+func (a AttackDefenceModel) Name() string {
+	return "attack_defence"
+}
+
+// Score samples a scoreline for the given match
+func (a AttackDefenceModel) Score(homeTeam, awayTeam models.Team, league *GenerateLeague) (int, int) {
+	homeAttack, homeDefence := adjustedRatings(homeTeam, league, true)
+	awayAttack, awayDefence := adjustedRatings(awayTeam, league, false)
+
+	homeGoals := a.ExpectedGoals(homeAttack, awayDefence)
+	awayGoals := a.ExpectedGoals(awayAttack, homeDefence)
+	return a.Goals.sampleScore(league.rng, homeGoals, awayGoals)
+}
+
+// ExpectedGoals converts an attack and the opposing defence into the goals
+// the attacking side is expected to score
+func (a AttackDefenceModel) ExpectedGoals(attack, defence float64) float64 {
+	if attack <= 0 || defence <= 0 {
+		return a.Goals.BaseGoals
+	}
+	return a.Goals.BaseGoals * math.Pow(attack/defence, a.RatingWeight)
+}
+
+// adjustedRatings returns a team's attack and defence scaled by the same home
+// advantage, form and Elo adjustments calculateTeamStrength applies to its
+// strength
+func adjustedRatings(team models.Team, league *GenerateLeague, isHome bool) (float64, float64) {
+	attack, defence := float64(team.Strength), float64(team.Strength)
+	if team.Attack != nil {
+		attack = float64(*team.Attack)
+	}
+	if team.Defence != nil {
+		defence = float64(*team.Defence)
+	}
+	if team.Strength <= 0 {
+		return attack, defence
+	}
+
+	scale := calculateTeamStrength(team, league, isHome) / float64(team.Strength)
+	return attack * scale, defence * scale
+}
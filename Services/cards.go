@@ -0,0 +1,45 @@
+package services
+
+import (
+	"insider-league/Models"
+	"math"
+	"math/rand"
+)
+
+// CardRates are the cards a team is expected to be shown in a match
+type CardRates struct {
+	Yellow float64
+	Red    float64
+}
+
+// DefaultCardRates match a typical top-flight season: close to two yellow
+// cards per team and match, and a red card every fifteen or so matches
+var DefaultCardRates = CardRates{
+	Yellow: 1.7,
+	Red:    0.07,
+}
+
+// cardSalt separates the random source of the cards from the one of the
+// scores, so adding cards did not change the scorelines of any seed
+const cardSalt = 0x63617264
+
+// sampleCards draws the cards shown to both teams of a match
+func (c CardRates) sampleCards(rng *rand.Rand) models.MatchCards {
+	return models.MatchCards{
+		HomeYellow: samplePoisson(rng, c.Yellow),
+		HomeRed:    samplePoisson(rng, c.Red),
+		AwayYellow: samplePoisson(rng, c.Yellow),
+		AwayRed:    samplePoisson(rng, c.Red),
+	}
+}
+
+// samplePoisson draws a count from a Poisson distribution with the given
+// mean by multiplying uniform draws until they fall below e^-mean
+func samplePoisson(rng *rand.Rand, mean float64) int {
+	limit := math.Exp(-mean)
+	count := 0
+	for product := rng.Float64(); product > limit; product *= rng.Float64() {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSampleCardsAverages(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const matches = 20000
+
+	var yellow, red int
+	for i := 0; i < matches; i++ {
+		cards := DefaultCardRates.sampleCards(rng)
+		yellow += cards.HomeYellow + cards.AwayYellow
+		red += cards.HomeRed + cards.AwayRed
+	}
+
+	if got := float64(yellow) / (2 * matches); got < 1.65 || got > 1.75 {
+		t.Errorf("%.3f yellow cards per team and match, want about %.2f", got, DefaultCardRates.Yellow)
+	}
+	if got := float64(red) / (2 * matches); got < 0.06 || got > 0.08 {
+		t.Errorf("%.3f red cards per team and match, want about %.2f", got, DefaultCardRates.Red)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"insider-league/Models"
+	"math"
+)
+
+// Rating systems a league can use. With Elo every team carries a rating that
+// moves after each match; without one only the static strength is used.
+const (
+	RatingsNone = "none"
+	RatingsElo  = "elo"
+)
+
+// EloParams tune how ratings move after a match
+type EloParams struct {
+	KFactor       float64 // rating points at stake in a one-goal match
+	HomeAdvantage float64 // rating points added to the home team when predicting the result
+}
+
+// DefaultEloParams are used by every league. They can be changed at startup,
+// before any league is simulated.
+var DefaultEloParams = EloParams{
+	KFactor:       20,
+	HomeAdvantage: 60,
+}
+
+// Ratings are anchored to strengths: a strength of 50 starts at 1500 and each
+// point of strength is worth ratingPerStrength rating points
+const (
+	baseRating        = 1500.0
+	ratingPerStrength = 10.0
+)
+
+// InitialRating is the rating a team starts a league with
+func InitialRating(strength int) float64 {
+	return baseRating + float64(strength-50)*ratingPerStrength
+}
+
+// RatingStrength converts a rating back to the strength scale used by the
+// match engines, so a team that has not played yet simulates as before
+func RatingStrength(rating float64) float64 {
+	return math.Max(1, 50+(rating-baseRating)/ratingPerStrength)
+}
+
+// ExpectedScore is the share of the points the home team is expected to
+// take: 1 for a certain win, 0.5 for even chances
+func (p EloParams) ExpectedScore(homeRating, awayRating float64) float64 {
+	return 1 / (1 + math.Pow(10, (awayRating-homeRating-p.HomeAdvantage)/400))
+}
+
+// RatingChange returns how much the home team's rating moves after a match;
+// the away team moves by the same amount the other way. Wider winning
+// margins move the ratings further.
+func (p EloParams) RatingChange(homeRating, awayRating float64, homeScore, awayScore int) float64 {
+	actual := 0.5
+	if homeScore > awayScore {
+		actual = 1
+	} else if homeScore < awayScore {
+		actual = 0
+	}
+
+	return p.KFactor * goalMarginFactor(homeScore-awayScore) * (actual - p.ExpectedScore(homeRating, awayRating))
+}
+
+// goalMarginFactor scales the rating change by the goal margin, as in the
+// World Football Elo ratings: 1 up to one goal, 1.5 for two, and (11+N)/8
+// for N goals beyond that
+func goalMarginFactor(margin int) float64 {
+	if margin < 0 {
+		margin = -margin
+	}
+	switch {
+	case margin <= 1:
+		return 1
+	case margin == 2:
+		return 1.5
+	default:
+		return (11 + float64(margin)) / 8
+	}
+}
+
+// Rating returns the current rating of a team of the league
+func (l *GenerateLeague) Rating(teamName string) float64 {
+	if rating, exists := l.ratings[teamName]; exists {
+		return rating
+	}
+	return InitialRating(l.teamStrength(teamName))
+}
+
+// SetRatings replaces the current ratings, for example with the stored ones
+// after the last played week. Teams without a rating start from their
+// strength.
+func (l *GenerateLeague) SetRatings(ratings map[string]float64) {
+	l.ratings = make(map[string]float64, len(ratings))
+	for name, rating := range ratings {
+		l.ratings[name] = rating
+	}
+}
+
+// rebuildRatings recomputes every rating from the played matches
+func (l *GenerateLeague) rebuildRatings(played []models.Match) {
+	l.ratings = make(map[string]float64)
+	for _, match := range played {
+		l.recordRating(match)
+	}
+}
+
+// recordRating moves the ratings of both teams after a match when the league
+// uses Elo ratings
+func (l *GenerateLeague) recordRating(match models.Match) {
+	if l.RatingSystem != RatingsElo {
+		return
+	}
+	if l.ratings == nil {
+		l.ratings = make(map[string]float64)
+	}
+
+	home, away := l.Rating(match.HomeTeam), l.Rating(match.AwayTeam)
+	change := DefaultEloParams.RatingChange(home, away, match.HomeScore, match.AwayScore)
+	l.ratings[match.HomeTeam] = home + change
+	l.ratings[match.AwayTeam] = away - change
+}
+
+// RatingHistory returns the rating of every team after each week from
+// fromWeek to toWeek. It starts from the ratings in start, the ones after
+// fromWeek, and replays the played matches of the weeks after it; teams
+// missing from start begin at the rating of their strength. Ratings are only
+// ever worked out from the ones before them, so a stored history keeps its
+// past weeks when a team's strength changes.
+func RatingHistory(teams []models.Team, start map[string]float64, fromWeek, toWeek int, played []models.Match) []models.TeamRating {
+	league := &GenerateLeague{Teams: teams, RatingSystem: RatingsElo}
+	league.SetRatings(start)
+
+	var history []models.TeamRating
+	record := func(week int) {
+		for _, team := range teams {
+			history = append(history, models.TeamRating{
+				TeamName: team.Name,
+				Week:     week,
+				Rating:   league.Rating(team.Name),
+			})
+		}
+	}
+
+	record(fromWeek)
+	for week := fromWeek + 1; week <= toWeek; week++ {
+		for _, match := range played {
+			if match.Week == week && match.Status == models.MatchPlayed {
+				league.recordRating(match)
+			}
+		}
+		record(week)
+	}
+	return history
+}
+
+// RatingsAfter picks every team's rating after the given week out of a
+// rating history
+func RatingsAfter(history []models.TeamRating, week int) map[string]float64 {
+	ratings := make(map[string]float64)
+	for _, rating := range history {
+		if rating.Week == week {
+			ratings[rating.TeamName] = rating.Rating
+		}
+	}
+	return ratings
+}
+
+// ValidateRatings checks the rating options of a league
+func ValidateRatings(system string, simulateWithRatings bool) error {
+	if system != RatingsNone && system != RatingsElo {
+		return fmt.Errorf("unknown rating system %q", system)
+	}
+	if simulateWithRatings && system != RatingsElo {
+		return fmt.Errorf("simulate_with_ratings needs the elo rating system")
+	}
+	return nil
+}
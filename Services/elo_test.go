@@ -0,0 +1,46 @@
+package services
+
+import (
+	"insider-league/Models"
+	"testing"
+)
+
+func TestRatingHistoryFromStoredWeek(t *testing.T) {
+	teams := testTeams(4)
+	played := []models.Match{
+		{HomeTeam: "T1", AwayTeam: "T2", HomeScore: 2, AwayScore: 0, Week: 1, Status: models.MatchPlayed},
+		{HomeTeam: "T3", AwayTeam: "T4", HomeScore: 1, AwayScore: 1, Week: 1, Status: models.MatchPlayed},
+		{HomeTeam: "T2", AwayTeam: "T3", HomeScore: 0, AwayScore: 3, Week: 2, Status: models.MatchPlayed},
+		{HomeTeam: "T4", AwayTeam: "T1", HomeScore: 1, AwayScore: 0, Week: 2, Status: models.MatchPlayed},
+		{HomeTeam: "T1", AwayTeam: "T3", HomeScore: 2, AwayScore: 2, Week: 3, Status: models.MatchPlayed},
+	}
+
+	full := RatingHistory(teams, nil, 0, 3, played)
+	if len(full) != 4*len(teams) {
+		t.Fatalf("got %d ratings, want %d", len(full), 4*len(teams))
+	}
+	for _, rating := range RatingsAfter(full, 0) {
+		if rating < 1400 || rating > 1600 {
+			t.Errorf("week 0 rating %.2f is not anchored to the strengths", rating)
+		}
+	}
+
+	// Going on from the stored week 1 gives the same later weeks
+	resumed := RatingHistory(testTeams(4), RatingsAfter(full, 1), 1, 3, played)
+	for week := 1; week <= 3; week++ {
+		want, got := RatingsAfter(full, week), RatingsAfter(resumed, week)
+		for _, team := range teams {
+			if got[team.Name] != want[team.Name] {
+				t.Errorf("week %d: %s = %.2f, want %.2f", week, team.Name, got[team.Name], want[team.Name])
+			}
+		}
+	}
+
+	// Stored ratings win over the current strengths
+	stronger := testTeams(4)
+	stronger[0].Strength = 99
+	start := RatingsAfter(full, 0)
+	if got := RatingsAfter(RatingHistory(stronger, start, 0, 0, nil), 0)["T1"]; got != start["T1"] {
+		t.Errorf("stored week 0 rating of T1 became %.2f, want %.2f", got, start["T1"])
+	}
+}
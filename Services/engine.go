@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"insider-league/Models"
+	"sort"
+)
+
+// MatchEngine produces the score of a single match. Engines only decide the
+// scoreline; PlayMatch records the result in the league table.
+type MatchEngine interface {
+	Name() string
+	Score(homeTeam, awayTeam models.Team, league *GenerateLeague) (int, int)
+}
+
+// DefaultEngineName is the engine used when a league does not choose one
+const DefaultEngineName = "poisson"
+
+// engines holds every registered engine by name
+var engines = make(map[string]MatchEngine)
+
+func init() {
+	RegisterEngine(ClassicEngine{})
+	RegisterEngine(DefaultPoissonModel)
+	RegisterEngine(DefaultAttackDefenceModel)
+}
+
+// RegisterEngine makes an engine available by its name, replacing any
+// engine registered under the same name
+func RegisterEngine(engine MatchEngine) {
+	engines[engine.Name()] = engine
+}
+
+// GetEngine looks up a registered engine by name
+func GetEngine(name string) (MatchEngine, error) {
+	engine, exists := engines[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown match engine %q", name)
+	}
+	return engine, nil
+}
+
+// EngineNames returns the names of all registered engines in sorted order
+func EngineNames() []string {
+	var names []string
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ClassicEngine is the original engine: it picks a winner from the strength
+// ratio, builds scores with generateScore and may turn the result into a draw
+type ClassicEngine struct{}
+
+// Name returns the registry name of the classic engine
+func (ClassicEngine) Name() string {
+	return "classic"
+}
+
+// Score samples a scoreline with the classic logic
+func (ClassicEngine) Score(homeTeam, awayTeam models.Team, league *GenerateLeague) (int, int) {
+	return classicScore(homeTeam, awayTeam, league)
+}
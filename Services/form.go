@@ -0,0 +1,156 @@
+package services
+
+import (
+	"insider-league/Models"
+	"math"
+	"strings"
+)
+
+// Form is a team's record over its most recent matches
+type Form struct {
+	Results string  // one letter per match, oldest first, such as "WWDLW"
+	Rating  float64 // between -1 (lost them all) and 1 (won them all)
+}
+
+// formEntry is one match in a team's form
+type formEntry struct {
+	result           byte // 'W', 'D' or 'L'
+	strength         int  // the team's current strength, not the one it had in the match
+	opponentStrength int
+}
+
+// SetResults replaces the played matches of the league, which must be in the
+// order they were played, and rebuilds the form and ratings of every team
+// from those up to the current week. Results entered by hand for later weeks
+// only count once their week is played. The table is left alone, since
+// stored leagues restore it separately.
+func (l *GenerateLeague) SetResults(played []models.Match) {
+	l.Results = append([]models.Match(nil), played...)
+
+	var current []models.Match
+	for _, match := range played {
+		if match.Week <= l.CurrentWeek {
+			current = append(current, match)
+		}
+	}
+	l.rebuildForm(current)
+	l.rebuildRatings(current)
+}
+
+// rebuildForm recomputes the form of every team from the played matches
+func (l *GenerateLeague) rebuildForm(played []models.Match) {
+	l.form = make(map[string][]formEntry)
+	for _, match := range played {
+		l.recordForm(match)
+	}
+}
+
+// recordForm adds a played match to the form of both teams, keeping only the
+// last FormWindow matches of each
+func (l *GenerateLeague) recordForm(match models.Match) {
+	if l.form == nil {
+		l.form = make(map[string][]formEntry)
+	}
+
+	homeStrength := l.teamStrength(match.HomeTeam)
+	awayStrength := l.teamStrength(match.AwayTeam)
+	home := formEntry{result: 'D', strength: homeStrength, opponentStrength: awayStrength}
+	away := formEntry{result: 'D', strength: awayStrength, opponentStrength: homeStrength}
+	if match.HomeScore > match.AwayScore {
+		home.result, away.result = 'W', 'L'
+	} else if match.HomeScore < match.AwayScore {
+		home.result, away.result = 'L', 'W'
+	}
+
+	l.form[match.HomeTeam] = l.appendForm(l.form[match.HomeTeam], home)
+	l.form[match.AwayTeam] = l.appendForm(l.form[match.AwayTeam], away)
+}
+
+// appendForm adds an entry to a team's form and drops the ones that have
+// fallen out of the window. It never writes to the array of recent, so a
+// clone sharing it is not affected.
+func (l *GenerateLeague) appendForm(recent []formEntry, entry formEntry) []formEntry {
+	window := l.formWindow()
+	if len(recent) >= window {
+		recent = recent[len(recent)-window+1:]
+	}
+	updated := make([]formEntry, len(recent), len(recent)+1)
+	copy(updated, recent)
+	return append(updated, entry)
+}
+
+// formWindow is the number of matches form looks back over
+func (l *GenerateLeague) formWindow() int {
+	if l.Params.FormWindow < 1 {
+		return 1
+	}
+	return l.Params.FormWindow
+}
+
+// teamStrength returns the strength of a team of the league, 0 if unknown
+func (l *GenerateLeague) teamStrength(teamName string) int {
+	for _, team := range l.Teams {
+		if team.Name == teamName {
+			return team.Strength
+		}
+	}
+	return 0
+}
+
+// Form returns the form of a team over the league's form window.
+//
+// Each result is worth 1 for a win, 0 for a draw and -1 for a loss, adjusted
+// for the opponent: a win against a stronger side is worth more, a loss to
+// one costs less, and a draw counts for the team holding a stronger side and
+// against the team held by a weaker one. The rating is the average of those
+// values with the most recent match weighted highest; every older match
+// weighs FormDecay times the one after it.
+func (l *GenerateLeague) Form(teamName string) Form {
+	recent := l.form[teamName]
+	if len(recent) == 0 {
+		return Form{}
+	}
+
+	decay := l.Params.FormDecay
+	if decay <= 0 || decay > 1 {
+		decay = 1
+	}
+
+	var results strings.Builder
+	var total, weights float64
+	weight := 1.0
+	for i := len(recent) - 1; i >= 0; i-- {
+		entry := recent[i]
+		quality := opponentQuality(entry.strength, entry.opponentStrength)
+
+		var value float64
+		switch entry.result {
+		case 'W':
+			value = quality
+		case 'D':
+			value = (quality - 1) / 2
+		case 'L':
+			value = -1 / quality
+		}
+
+		total += weight * value
+		weights += weight
+		weight *= decay
+	}
+	for _, entry := range recent {
+		results.WriteByte(entry.result)
+	}
+
+	rating := math.Max(-1, math.Min(1, total/weights))
+	return Form{Results: results.String(), Rating: rating}
+}
+
+// opponentQuality is how much stronger the opponent was, as a ratio kept
+// between 0.5 and 2. Unknown strengths count as an even match.
+func opponentQuality(strength, opponentStrength int) float64 {
+	if strength <= 0 || opponentStrength <= 0 {
+		return 1
+	}
+	quality := float64(opponentStrength) / float64(strength)
+	return math.Max(0.5, math.Min(2, quality))
+}
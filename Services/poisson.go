@@ -0,0 +1,113 @@
+package services
+
+import (
+	"insider-league/Models"
+	"math"
+	"math/rand"
+)
+
+// PoissonModel turns the adjusted strengths of two teams into expected goals
+// and samples each side's goals from a Poisson distribution. A non-zero Rho
+// applies the Dixon-Coles correction, which makes low-scoring draws slightly
+// more likely than independent Poisson draws would.
+type PoissonModel struct {
+	BaseGoals      float64 // expected goals per side when both teams are equal
+	StrengthWeight float64 // how strongly the strength ratio moves expected goals
+	Rho            float64 // Dixon-Coles dependence parameter, 0 disables it
+	MaxGoals       int     // highest score considered per side
+}
+
+// DefaultPoissonModel is the engine registered as "poisson"
+var DefaultPoissonModel = PoissonModel{
+	BaseGoals:      1.35,
+	StrengthWeight: 1.0,
+	Rho:            -0.1,
+	MaxGoals:       10,
+}
+
+// Name returns the registry name of the Poisson engine
+func (p PoissonModel) Name() string {
+	return "poisson"
+}
+
+// Score samples a scoreline for the given match
+func (p PoissonModel) Score(homeTeam, awayTeam models.Team, league *GenerateLeague) (int, int) {
+	// Home advantage and form are part of the adjusted strengths
+	homeStrength := calculateTeamStrength(homeTeam, league, true)
+	awayStrength := calculateTeamStrength(awayTeam, league, false)
+
+	homeGoals, awayGoals := p.ExpectedGoals(homeStrength, awayStrength)
+	return p.sampleScore(league.rng, homeGoals, awayGoals)
+}
+
+// ExpectedGoals converts two adjusted strengths into expected goals per side
+func (p PoissonModel) ExpectedGoals(homeStrength, awayStrength float64) (float64, float64) {
+	if homeStrength <= 0 || awayStrength <= 0 {
+		return p.BaseGoals, p.BaseGoals
+	}
+
+	ratio := math.Pow(homeStrength/awayStrength, p.StrengthWeight)
+	return p.BaseGoals * ratio, p.BaseGoals / ratio
+}
+
+// sampleScore draws a scoreline from the joint score distribution
+func (p PoissonModel) sampleScore(rng *rand.Rand, homeGoals, awayGoals float64) (int, int) {
+	maxGoals := p.MaxGoals
+	if maxGoals < 1 {
+		maxGoals = 10
+	}
+
+	homeProbs := poissonProbabilities(homeGoals, maxGoals)
+	awayProbs := poissonProbabilities(awayGoals, maxGoals)
+
+	// Build the joint distribution, applying the low-score correction
+	joint := make([]float64, 0, (maxGoals+1)*(maxGoals+1))
+	total := 0.0
+	for home := 0; home <= maxGoals; home++ {
+		for away := 0; away <= maxGoals; away++ {
+			prob := homeProbs[home] * awayProbs[away] * p.lowScoreCorrection(home, away, homeGoals, awayGoals)
+			if prob < 0 {
+				prob = 0
+			}
+			joint = append(joint, prob)
+			total += prob
+		}
+	}
+
+	// Pick a cell of the table proportionally to its probability
+	target := rng.Float64() * total
+	for index, prob := range joint {
+		target -= prob
+		if target < 0 {
+			return index / (maxGoals + 1), index % (maxGoals + 1)
+		}
+	}
+
+	return maxGoals, maxGoals
+}
+
+// lowScoreCorrection is the Dixon-Coles tau factor for the 0-0, 1-0, 0-1 and 1-1 scores
+func (p PoissonModel) lowScoreCorrection(home, away int, homeGoals, awayGoals float64) float64 {
+	switch {
+	case home == 0 && away == 0:
+		return 1 - homeGoals*awayGoals*p.Rho
+	case home == 0 && away == 1:
+		return 1 + homeGoals*p.Rho
+	case home == 1 && away == 0:
+		return 1 + awayGoals*p.Rho
+	case home == 1 && away == 1:
+		return 1 - p.Rho
+	default:
+		return 1
+	}
+}
+
+// poissonProbabilities returns P(X = k) for k = 0..maxGoals
+func poissonProbabilities(lambda float64, maxGoals int) []float64 {
+	probs := make([]float64, maxGoals+1)
+	probs[0] = math.Exp(-lambda)
+	for k := 1; k <= maxGoals; k++ {
+		probs[k] = probs[k-1] * lambda / float64(k)
+	}
+	return probs
+}
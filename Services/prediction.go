@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"insider-league/Models"
+	"math/rand"
+)
+
+// SeasonPrediction holds the Monte Carlo outcome for a single team
+type SeasonPrediction struct {
+	TeamName            string
+	ChampionProbability float64
+	TopProbability      float64
+	BottomProbability   float64
+	ExpectedPoints      float64
+}
+
+// PredictSeason simulates the remaining fixtures runs times, starting from the
+// current state of the league, and returns per-team probabilities of finishing
+// first, inside the top topN and inside the bottom bottomN positions. It
+// stops with the context's error as soon as ctx is done.
+func PredictSeason(ctx context.Context, league *GenerateLeague, remaining [][]models.Match, runs, topN, bottomN int) ([]SeasonPrediction, error) {
+	if runs < 1 || len(league.TeamStats) == 0 {
+		return []SeasonPrediction{}, nil
+	}
+
+	teamCount := len(league.TeamStats)
+	if topN > teamCount {
+		topN = teamCount
+	}
+	if bottomN > teamCount {
+		bottomN = teamCount
+	}
+
+	teamsByName := make(map[string]models.Team)
+	for _, team := range league.Teams {
+		teamsByName[team.Name] = team
+	}
+
+	// One random source for all runs keeps predictions reproducible for a
+	// given league seed and week
+	rng := rand.New(rand.NewSource(WeekSeed(league.Seed, league.CurrentWeek) ^ predictionSalt))
+	cardRng := rand.New(rand.NewSource(WeekSeed(league.Seed, league.CurrentWeek) ^ predictionSalt ^ cardSalt))
+
+	titles := make(map[string]int)
+	tops := make(map[string]int)
+	bottoms := make(map[string]int)
+	totalPoints := make(map[string]int)
+
+	for run := 0; run < runs; run++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		sim := league.clone()
+		sim.rng = rng
+		sim.cardRng = cardRng
+
+		for _, weekMatches := range remaining {
+			for _, fixture := range weekMatches {
+				homeTeam, okHome := teamsByName[fixture.HomeTeam]
+				awayTeam, okAway := teamsByName[fixture.AwayTeam]
+				if !okHome || !okAway {
+					continue
+				}
+
+				match, err := PlayMatch(homeTeam, awayTeam, sim)
+				if err != nil {
+					continue
+				}
+				match.Week = fixture.Week
+				sim.Results = append(sim.Results, match)
+			}
+		}
+
+		standings := sim.GetLeagueTable()
+		for position, stats := range standings {
+			if position == 0 {
+				titles[stats.TeamName]++
+			}
+			if position < topN {
+				tops[stats.TeamName]++
+			}
+			if position >= teamCount-bottomN {
+				bottoms[stats.TeamName]++
+			}
+			totalPoints[stats.TeamName] += stats.Points
+		}
+	}
+
+	// Report teams in the order of the current table
+	var predictions []SeasonPrediction
+	for _, stats := range league.GetLeagueTable() {
+		name := stats.TeamName
+		predictions = append(predictions, SeasonPrediction{
+			TeamName:            name,
+			ChampionProbability: float64(titles[name]) / float64(runs) * 100.0,
+			TopProbability:      float64(tops[name]) / float64(runs) * 100.0,
+			BottomProbability:   float64(bottoms[name]) / float64(runs) * 100.0,
+			ExpectedPoints:      float64(totalPoints[name]) / float64(runs),
+		})
+	}
+
+	return predictions, nil
+}
+
+// MaxPredictionMatches bounds the matches a single prediction may simulate,
+// runs times remaining fixtures, so one request stays within a few seconds
+const MaxPredictionMatches = 1000000
+
+// PredictionRuns caps the requested runs to the match budget for the
+// remaining fixtures; at least one run is always allowed
+func PredictionRuns(runs int, remaining [][]models.Match) int {
+	matches := 0
+	for _, week := range remaining {
+		matches += len(week)
+	}
+	if matches > 0 && runs > MaxPredictionMatches/matches {
+		runs = MaxPredictionMatches / matches
+	}
+	if runs < 1 {
+		runs = 1
+	}
+	return runs
+}
+
+// predictionSalt separates the prediction random stream from the one used to play weeks
+const predictionSalt = 0x5eed
+
+// clone returns a copy of the league whose stats and results can be changed
+// without affecting the original
+func (l *GenerateLeague) clone() *GenerateLeague {
+	copied := &GenerateLeague{
+		Teams:               l.Teams,
+		Fixtures:            l.Fixtures,
+		Results:             make([]models.Match, len(l.Results)),
+		CurrentWeek:         l.CurrentWeek,
+		TeamStats:           make(map[string]*TeamStats, len(l.TeamStats)),
+		Seed:                l.Seed,
+		Engine:              l.Engine,
+		Params:              l.Params,
+		Rules:               l.Rules,
+		RatingSystem:        l.RatingSystem,
+		SimulateWithRatings: l.SimulateWithRatings,
+		rng:                 l.rng,
+		cardRng:             l.cardRng,
+		form:                make(map[string][]formEntry, len(l.form)),
+		ratings:             make(map[string]float64, len(l.ratings)),
+	}
+	copy(copied.Results, l.Results)
+
+	// appendForm never writes to a shared array, so the entries can be shared
+	for name, recent := range l.form {
+		copied.form[name] = recent
+	}
+	for name, rating := range l.ratings {
+		copied.ratings[name] = rating
+	}
+
+	for name, stats := range l.TeamStats {
+		statsCopy := *stats
+		copied.TeamStats[name] = &statsCopy
+	}
+
+	return copied
+}
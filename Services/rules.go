@@ -0,0 +1,317 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"insider-league/Models"
+	"sort"
+)
+
+// Tie-breakers that order teams level on points, as named in league rules
+const (
+	TieBreakGoalDifference           = "goal_difference"
+	TieBreakGoalsFor                 = "goals_for"
+	TieBreakHeadToHeadPoints         = "head_to_head_points"
+	TieBreakHeadToHeadGoalDifference = "head_to_head_goal_difference"
+	TieBreakAwayGoals                = "away_goals"
+	TieBreakWins                     = "wins"
+	TieBreakFairPlay                 = "fair_play"
+	TieBreakDrawingOfLots            = "drawing_of_lots"
+)
+
+// Standings modes. In overall mode teams level on points go straight to the
+// tie-breakers; in head-to-head mode they are first ordered by a mini-league
+// of the matches between them, as in La Liga and Serie A.
+const (
+	StandingsOverall    = "overall"
+	StandingsHeadToHead = "head_to_head"
+)
+
+// tieBreakers lists every supported tie-breaker
+var tieBreakers = []string{
+	TieBreakGoalDifference,
+	TieBreakGoalsFor,
+	TieBreakHeadToHeadPoints,
+	TieBreakHeadToHeadGoalDifference,
+	TieBreakAwayGoals,
+	TieBreakWins,
+	TieBreakFairPlay,
+	TieBreakDrawingOfLots,
+}
+
+// TieBreakerNames returns the names of the supported tie-breakers
+func TieBreakerNames() []string {
+	names := make([]string, len(tieBreakers))
+	copy(names, tieBreakers)
+	return names
+}
+
+// DefaultRules returns the Premier League rules: 3 points for a win, 1 for a
+// draw, no bonus, and ties broken by goal difference then goals scored.
+// Every call returns a new value, so callers may change it.
+func DefaultRules() models.LeagueRules {
+	return models.LeagueRules{
+		PointsWin:     3,
+		PointsDraw:    1,
+		PointsLoss:    0,
+		TieBreakers:   []string{TieBreakGoalDifference, TieBreakGoalsFor},
+		StandingsMode: StandingsOverall,
+	}
+}
+
+// ValidateRules checks that a rules profile can rank a table: a win is worth
+// at least a draw, a draw at least a loss, the standings mode is known, and
+// every tie-breaker is known and listed once
+func ValidateRules(rules models.LeagueRules) error {
+	if rules.StandingsMode != StandingsOverall && rules.StandingsMode != StandingsHeadToHead {
+		return fmt.Errorf("unknown standings mode %q", rules.StandingsMode)
+	}
+	if rules.PointsWin < rules.PointsDraw || rules.PointsDraw < rules.PointsLoss {
+		return fmt.Errorf("points must not increase from win to draw to loss, got %d/%d/%d",
+			rules.PointsWin, rules.PointsDraw, rules.PointsLoss)
+	}
+	if rules.BonusPoints > 0 && rules.BonusGoals == 0 {
+		return fmt.Errorf("bonus_goals is required when bonus_points is set")
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range rules.TieBreakers {
+		known := false
+		for _, tieBreaker := range tieBreakers {
+			known = known || name == tieBreaker
+		}
+		if !known {
+			return fmt.Errorf("unknown tie-breaker %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("tie-breaker %q is listed more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// MatchPoints returns the points a team earns for one match under the rules,
+// including the bonus for scoring at least BonusGoals goals
+func MatchPoints(rules models.LeagueRules, goalsFor, goalsAgainst int) int {
+	points := rules.PointsDraw
+	if goalsFor > goalsAgainst {
+		points = rules.PointsWin
+	} else if goalsFor < goalsAgainst {
+		points = rules.PointsLoss
+	}
+
+	if rules.BonusGoals > 0 && goalsFor >= rules.BonusGoals {
+		points += rules.BonusPoints
+	}
+	return points
+}
+
+// RankStandings sorts standings by points and then by the tie-breakers of
+// the rules, in order; in head-to-head mode a mini-league of the matches
+// between teams level on points comes first. Tie-breakers looking at results
+// only use the played matches in results; seed makes the drawing of lots
+// repeatable. Teams still level after every tie-breaker are ordered by name.
+func RankStandings(standings []*TeamStats, results []models.Match, rules models.LeagueRules, seed int64) {
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].TeamName < standings[j].TeamName
+	})
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].Points > standings[j].Points
+	})
+
+	ranking := &tableRanking{results: results, rules: rules, seed: seed}
+	samePoints := func(a, b *TeamStats) bool { return a.Points == b.Points }
+	forEachTie(standings, samePoints, func(group []*TeamStats) {
+		if rules.StandingsMode == StandingsHeadToHead {
+			ranking.miniLeague(group)
+		} else {
+			ranking.breakTies(group, rules.TieBreakers)
+		}
+	})
+}
+
+// tableRanking holds what the tie-breakers need to separate teams
+type tableRanking struct {
+	results []models.Match
+	rules   models.LeagueRules
+	seed    int64
+}
+
+// breakTies orders a group of teams level so far by the first tie-breaker,
+// then hands each group still level to the next one
+func (t *tableRanking) breakTies(group []*TeamStats, chain []string) {
+	if len(group) < 2 || len(chain) == 0 {
+		return
+	}
+
+	scores := t.scores(chain[0], group)
+	sort.SliceStable(group, func(i, j int) bool {
+		return scores[group[i].TeamName] > scores[group[j].TeamName]
+	})
+
+	sameScore := func(a, b *TeamStats) bool { return scores[a.TeamName] == scores[b.TeamName] }
+	forEachTie(group, sameScore, func(tied []*TeamStats) {
+		t.breakTies(tied, chain[1:])
+	})
+}
+
+// miniLeague orders a group of teams level on points by a table of the
+// matches between them only: points, then goal difference, then goals
+// scored. When that leaves some of the teams level, the mini-league is
+// built again from the matches between those teams alone, so a result
+// against a team already separated no longer counts. Teams the mini-league
+// cannot separate at all go on to the tie-breakers.
+func (t *tableRanking) miniLeague(group []*TeamStats) {
+	if len(group) < 2 {
+		return
+	}
+
+	mini := t.miniTable(group)
+	ahead := func(a, b *TeamStats) bool {
+		x, y := mini[a.TeamName], mini[b.TeamName]
+		if x.points != y.points {
+			return x.points > y.points
+		}
+		if x.goalDiff != y.goalDiff {
+			return x.goalDiff > y.goalDiff
+		}
+		return x.goalsFor > y.goalsFor
+	}
+	sort.SliceStable(group, func(i, j int) bool {
+		return ahead(group[i], group[j])
+	})
+
+	level := func(a, b *TeamStats) bool { return mini[a.TeamName] == mini[b.TeamName] }
+	forEachTie(group, level, func(tied []*TeamStats) {
+		if len(tied) < len(group) {
+			t.miniLeague(tied)
+		} else {
+			t.breakTies(tied, t.rules.TieBreakers)
+		}
+	})
+}
+
+// miniStats is a team's record in the matches among a group of teams
+type miniStats struct {
+	points   int
+	goalDiff int
+	goalsFor int
+}
+
+// miniTable adds up the matches played between the teams of a group
+func (t *tableRanking) miniTable(group []*TeamStats) map[string]miniStats {
+	mini := make(map[string]miniStats, len(group))
+	for _, stats := range group {
+		mini[stats.TeamName] = miniStats{}
+	}
+
+	for _, match := range t.results {
+		home, okHome := mini[match.HomeTeam]
+		away, okAway := mini[match.AwayTeam]
+		if !okHome || !okAway {
+			continue
+		}
+
+		home.points += MatchPoints(t.rules, match.HomeScore, match.AwayScore)
+		home.goalDiff += match.HomeScore - match.AwayScore
+		home.goalsFor += match.HomeScore
+		away.points += MatchPoints(t.rules, match.AwayScore, match.HomeScore)
+		away.goalDiff += match.AwayScore - match.HomeScore
+		away.goalsFor += match.AwayScore
+
+		mini[match.HomeTeam] = home
+		mini[match.AwayTeam] = away
+	}
+	return mini
+}
+
+// scores rates every team of a group by one tie-breaker; higher ranks first
+func (t *tableRanking) scores(tieBreaker string, group []*TeamStats) map[string]int {
+	scores := make(map[string]int, len(group))
+	inGroup := make(map[string]bool, len(group))
+	for _, stats := range group {
+		inGroup[stats.TeamName] = true
+	}
+
+	switch tieBreaker {
+	case TieBreakGoalDifference:
+		for _, stats := range group {
+			scores[stats.TeamName] = stats.GoalsFor - stats.GoalsAgainst
+		}
+	case TieBreakGoalsFor:
+		for _, stats := range group {
+			scores[stats.TeamName] = stats.GoalsFor
+		}
+	case TieBreakWins:
+		for _, stats := range group {
+			scores[stats.TeamName] = stats.Won
+		}
+	case TieBreakAwayGoals:
+		for _, match := range t.results {
+			if inGroup[match.AwayTeam] {
+				scores[match.AwayTeam] += match.AwayScore
+			}
+		}
+	case TieBreakHeadToHeadPoints, TieBreakHeadToHeadGoalDifference:
+		// Only the matches between the teams of the group count
+		for _, match := range t.results {
+			if !inGroup[match.HomeTeam] || !inGroup[match.AwayTeam] {
+				continue
+			}
+			if tieBreaker == TieBreakHeadToHeadPoints {
+				scores[match.HomeTeam] += MatchPoints(t.rules, match.HomeScore, match.AwayScore)
+				scores[match.AwayTeam] += MatchPoints(t.rules, match.AwayScore, match.HomeScore)
+			} else {
+				scores[match.HomeTeam] += match.HomeScore - match.AwayScore
+				scores[match.AwayTeam] += match.AwayScore - match.HomeScore
+			}
+		}
+	case TieBreakFairPlay:
+		// Disciplinary points count against a team, so they are subtracted
+		for _, match := range t.results {
+			if inGroup[match.HomeTeam] {
+				scores[match.HomeTeam] -= FairPlayPoints(match.Cards.HomeYellow, match.Cards.HomeRed)
+			}
+			if inGroup[match.AwayTeam] {
+				scores[match.AwayTeam] -= FairPlayPoints(match.Cards.AwayYellow, match.Cards.AwayRed)
+			}
+		}
+	case TieBreakDrawingOfLots:
+		for _, stats := range group {
+			scores[stats.TeamName] = drawLot(t.seed, stats.TeamName)
+		}
+	}
+	return scores
+}
+
+// FairPlayPoints returns the disciplinary points of a team's cards in a
+// match, as in the UEFA fair play ranking: 1 for a yellow card and 3 for a
+// red one. Fewer points rank higher.
+func FairPlayPoints(yellow, red int) int {
+	return yellow + 3*red
+}
+
+// drawLot gives a team its lot for a league. The lot only depends on the
+// league seed and the team, so the draw comes out the same every time the
+// table is ranked.
+func drawLot(seed int64, teamName string) int {
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%d:%s", seed, teamName)
+	return int(hash.Sum32())
+}
+
+// forEachTie calls fn with every run of two or more neighbouring teams that
+// same reports as level
+func forEachTie(standings []*TeamStats, same func(a, b *TeamStats) bool, fn func([]*TeamStats)) {
+	for start := 0; start < len(standings); {
+		end := start + 1
+		for end < len(standings) && same(standings[start], standings[end]) {
+			end++
+		}
+		if end-start > 1 {
+			fn(standings[start:end])
+		}
+		start = end
+	}
+}
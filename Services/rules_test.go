@@ -0,0 +1,241 @@
+package services
+
+import (
+	"insider-league/Models"
+	"testing"
+)
+
+func TestValidateRules(t *testing.T) {
+	rules := func(change func(*models.LeagueRules)) models.LeagueRules {
+		r := DefaultRules()
+		change(&r)
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		rules   models.LeagueRules
+		wantErr bool
+	}{
+		{
+			name:  "default rules",
+			rules: DefaultRules(),
+		},
+		{
+			name: "every tie-breaker",
+			rules: rules(func(r *models.LeagueRules) {
+				r.TieBreakers = TieBreakerNames()
+				r.StandingsMode = StandingsHeadToHead
+			}),
+		},
+		{
+			name:    "unknown tie-breaker",
+			rules:   rules(func(r *models.LeagueRules) { r.TieBreakers = []string{"coin_toss"} }),
+			wantErr: true,
+		},
+		{
+			name: "repeated tie-breaker",
+			rules: rules(func(r *models.LeagueRules) {
+				r.TieBreakers = []string{TieBreakGoalDifference, TieBreakGoalDifference}
+			}),
+			wantErr: true,
+		},
+		{
+			name:    "unknown standings mode",
+			rules:   rules(func(r *models.LeagueRules) { r.StandingsMode = "ranked" }),
+			wantErr: true,
+		},
+		{
+			name:    "draw worth more than a win",
+			rules:   rules(func(r *models.LeagueRules) { r.PointsDraw = 4 }),
+			wantErr: true,
+		},
+		{
+			name:    "bonus points without bonus goals",
+			rules:   rules(func(r *models.LeagueRules) { r.BonusPoints = 1 }),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateRules(test.rules)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ValidateRules() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRankStandings(t *testing.T) {
+	team := func(name string, points, goalsFor, goalsAgainst int) *TeamStats {
+		return &TeamStats{TeamName: name, Points: points, GoalsFor: goalsFor, GoalsAgainst: goalsAgainst}
+	}
+	result := func(home string, homeScore, awayScore int, away string) models.Match {
+		return models.Match{HomeTeam: home, AwayTeam: away, HomeScore: homeScore, AwayScore: awayScore, Status: models.MatchPlayed}
+	}
+	booked := func(match models.Match, homeYellow, homeRed, awayYellow, awayRed int) models.Match {
+		match.Cards = models.MatchCards{HomeYellow: homeYellow, HomeRed: homeRed, AwayYellow: awayYellow, AwayRed: awayRed}
+		return match
+	}
+	overall := func(tieBreakers ...string) models.LeagueRules {
+		rules := DefaultRules()
+		rules.TieBreakers = tieBreakers
+		return rules
+	}
+	headToHead := func(tieBreakers ...string) models.LeagueRules {
+		rules := overall(tieBreakers...)
+		rules.StandingsMode = StandingsHeadToHead
+		return rules
+	}
+
+	tests := []struct {
+		name      string
+		standings []*TeamStats
+		results   []models.Match
+		rules     models.LeagueRules
+		want      []string
+	}{
+		{
+			name:      "points come first",
+			standings: []*TeamStats{team("Ajax", 3, 1, 9), team("Benfica", 6, 2, 2)},
+			rules:     overall(TieBreakGoalDifference),
+			want:      []string{"Benfica", "Ajax"},
+		},
+		{
+			name:      "two-way tie on goal difference",
+			standings: []*TeamStats{team("Ajax", 6, 4, 3), team("Benfica", 6, 5, 2)},
+			rules:     overall(TieBreakGoalDifference, TieBreakGoalsFor),
+			want:      []string{"Benfica", "Ajax"},
+		},
+		{
+			name:      "two-way tie level on goal difference goes to goals for",
+			standings: []*TeamStats{team("Ajax", 6, 4, 2), team("Benfica", 6, 6, 4)},
+			rules:     overall(TieBreakGoalDifference, TieBreakGoalsFor),
+			want:      []string{"Benfica", "Ajax"},
+		},
+		{
+			name: "three-way tie partly broken goes to the next tie-breaker",
+			standings: []*TeamStats{
+				team("Ajax", 6, 5, 4), team("Benfica", 6, 7, 6), team("Celtic", 6, 6, 3),
+			},
+			rules: overall(TieBreakGoalDifference, TieBreakGoalsFor),
+			want:  []string{"Celtic", "Benfica", "Ajax"},
+		},
+		{
+			name: "head-to-head points as a tie-breaker",
+			standings: []*TeamStats{
+				team("Ajax", 6, 9, 1), team("Benfica", 6, 2, 2), team("Celtic", 9, 3, 3),
+			},
+			results: []models.Match{result("Benfica", 1, 0, "Ajax"), result("Ajax", 1, 0, "Celtic")},
+			rules:   overall(TieBreakHeadToHeadPoints, TieBreakGoalDifference),
+			want:    []string{"Celtic", "Benfica", "Ajax"},
+		},
+		{
+			name:      "two-way tie on fair play",
+			standings: []*TeamStats{team("Ajax", 6, 4, 4), team("Benfica", 6, 4, 4), team("Celtic", 3, 1, 1)},
+			results: []models.Match{
+				booked(result("Ajax", 1, 1, "Celtic"), 2, 1, 0, 0),
+				booked(result("Celtic", 0, 2, "Benfica"), 4, 0, 3, 0),
+			},
+			rules: overall(TieBreakGoalDifference, TieBreakFairPlay),
+			want:  []string{"Benfica", "Ajax", "Celtic"},
+		},
+		{
+			name:      "level on fair play goes to the next tie-breaker",
+			standings: []*TeamStats{team("Ajax", 6, 4, 4), team("Benfica", 6, 5, 5)},
+			results: []models.Match{
+				booked(result("Ajax", 1, 1, "Benfica"), 3, 0, 0, 1),
+			},
+			rules: overall(TieBreakFairPlay, TieBreakGoalsFor),
+			want:  []string{"Benfica", "Ajax"},
+		},
+		{
+			name:      "level after every tie-breaker by name",
+			standings: []*TeamStats{team("Celtic", 6, 4, 4), team("Ajax", 6, 4, 4), team("Benfica", 6, 4, 4)},
+			rules:     overall(TieBreakGoalDifference, TieBreakGoalsFor),
+			want:      []string{"Ajax", "Benfica", "Celtic"},
+		},
+		{
+			name:      "mini-league two-way tie",
+			standings: []*TeamStats{team("Ajax", 6, 8, 2), team("Benfica", 6, 3, 3)},
+			results:   []models.Match{result("Ajax", 0, 1, "Benfica")},
+			rules:     headToHead(TieBreakGoalDifference),
+			want:      []string{"Benfica", "Ajax"},
+		},
+		{
+			name:      "mini-league two-way draw goes to the tie-breakers",
+			standings: []*TeamStats{team("Ajax", 6, 3, 3), team("Benfica", 6, 8, 2)},
+			results:   []models.Match{result("Ajax", 1, 1, "Benfica")},
+			rules:     headToHead(TieBreakGoalDifference),
+			want:      []string{"Benfica", "Ajax"},
+		},
+		{
+			// Ajax tops the mini-league; Benfica and Celtic are level in it,
+			// so it is built again from their match alone, which Celtic won
+			name: "mini-league three-way tie rebuilt for the teams still level",
+			standings: []*TeamStats{
+				team("Ajax", 9, 4, 4), team("Benfica", 9, 9, 2), team("Celtic", 9, 3, 3),
+			},
+			results: []models.Match{
+				result("Celtic", 1, 0, "Benfica"),
+				result("Ajax", 2, 0, "Celtic"),
+				result("Benfica", 1, 0, "Ajax"),
+				result("Ajax", 1, 0, "Benfica"),
+			},
+			rules: headToHead(TieBreakGoalDifference),
+			want:  []string{"Ajax", "Celtic", "Benfica"},
+		},
+		{
+			name: "mini-league three-way tie unresolved goes to the tie-breakers",
+			standings: []*TeamStats{
+				team("Ajax", 6, 6, 3), team("Benfica", 6, 4, 3), team("Celtic", 6, 8, 3),
+			},
+			results: []models.Match{
+				result("Ajax", 1, 1, "Benfica"),
+				result("Benfica", 1, 1, "Celtic"),
+				result("Celtic", 1, 1, "Ajax"),
+			},
+			rules: headToHead(TieBreakGoalDifference),
+			want:  []string{"Celtic", "Ajax", "Benfica"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			RankStandings(test.standings, test.results, test.rules, 1)
+
+			var got []string
+			for _, stats := range test.standings {
+				got = append(got, stats.TeamName)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRankStandingsDrawingOfLotsIsRepeatable(t *testing.T) {
+	standings := func() []*TeamStats {
+		var teams []*TeamStats
+		for _, name := range []string{"Ajax", "Benfica", "Celtic", "Dynamo", "Everton"} {
+			teams = append(teams, &TeamStats{TeamName: name, Points: 6})
+		}
+		return teams
+	}
+	rules := DefaultRules()
+	rules.TieBreakers = []string{TieBreakDrawingOfLots}
+
+	first, second := standings(), standings()
+	RankStandings(first, nil, rules, 7)
+	RankStandings(second, nil, rules, 7)
+	for i := range first {
+		if first[i].TeamName != second[i].TeamName {
+			t.Fatalf("the same seed drew %s and %s at position %d", first[i].TeamName, second[i].TeamName, i+1)
+		}
+	}
+}
@@ -1,12 +1,12 @@
 package services
 
-
 import (
+	"errors"
+	"fmt"
 	"insider-league/Models"
 	"math"
 	"math/rand"
-	"errors"
-	"sort"
+	"time"
 )
 
 // LeagueSimulator defines the core league operations
@@ -17,11 +17,38 @@ type LeagueSimulator interface {
 }
 
 type GenerateLeague struct {
-	Teams []models.Team
-	Fixtures [][]models.Match
-	Results []models.Match
-	CurrentWeek int
-	TeamStats map[string]*TeamStats
+	Teams               []models.Team
+	Fixtures            [][]models.Match
+	Results             []models.Match
+	CurrentWeek         int
+	TeamStats           map[string]*TeamStats
+	Seed                int64
+	Engine              MatchEngine
+	Params              SimulationParams
+	Rules               models.LeagueRules
+	RatingSystem        string // RatingsNone or RatingsElo
+	SimulateWithRatings bool   // play matches with Elo ratings instead of the static strengths
+	rng                 *rand.Rand
+	cardRng             *rand.Rand             // draws the cards, apart from rng so the scores do not depend on them
+	form                map[string][]formEntry // recent results of each team, oldest first
+	ratings             map[string]float64     // current Elo rating of each team that has played
+}
+
+// SimulationParams tune how a team's strength is adjusted before each match
+type SimulationParams struct {
+	HomeAdvantage float64 // strength multiplier for the home team
+	FormBonus     float64 // strength change at the best form rating, subtracted at the worst
+	FormWindow    int     // number of recent matches form looks back over
+	FormDecay     float64 // weight of each match relative to the one after it
+}
+
+// DefaultSimulationParams are given to every new league. They can be
+// changed at startup, before any league is simulated.
+var DefaultSimulationParams = SimulationParams{
+	HomeAdvantage: 1.03, // 3% boost
+	FormBonus:     0.05, // up to 5% boost in good form, 5% penalty in bad form
+	FormWindow:    5,    // the last five matches
+	FormDecay:     0.8,  // the previous match counts 80% as much as the latest
 }
 
 // TeamStats tracks individual team performance
@@ -37,188 +64,321 @@ type TeamStats struct {
 	GoalDiff     int
 }
 
-func GenerateFixture(teams []models.Team) [][]models.Match {
+// Round is a single week of a round-robin schedule. For an odd number of
+// teams one team sits out every round and is recorded in Bye.
+type Round struct {
+	Week    int
+	Matches []models.Match
+	Bye     string
+}
+
+// Supported numbers of legs: single, double and quadruple round robin
+const (
+	SingleRoundRobin    = 1
+	DoubleRoundRobin    = 2
+	QuadrupleRoundRobin = 4
+)
+
+// ValidLegs reports whether a league can be scheduled with the given number of legs
+func ValidLegs(legs int) bool {
+	return legs == SingleRoundRobin || legs == DoubleRoundRobin || legs == QuadrupleRoundRobin
+}
+
+// GenerateRounds builds a double round-robin schedule using the circle
+// (Berger) method. Every pair of teams meets exactly once at home and once
+// away; the second half of the season mirrors the first with venues swapped.
+func GenerateRounds(teams []models.Team) []Round {
+	return GenerateRoundsWithLegs(teams, DoubleRoundRobin)
+}
+
+// GenerateRoundsWithLegs builds a round-robin schedule with the given number
+// of legs. Every leg is a full circle-method round robin and each leg mirrors
+// the previous one with venues swapped.
+//
+// Venues are balanced within a leg. With an odd number of teams everyone
+// plays an even number of matches per leg and hosts exactly half of them.
+// With an even number every team plays an odd number, so it hosts one more
+// match than it plays away, or one fewer. A double round robin is balanced
+// exactly either way.
+func GenerateRoundsWithLegs(teams []models.Team, legs int) []Round {
 	// Input validation
-	if len(teams) < 2 {
-		return [][]models.Match{} // Return empty fixtures for invalid input
+	if len(teams) < 2 || !ValidLegs(legs) {
+		return []Round{}
 	}
-	
-	var fixtures [][]models.Match
-	teamCount := len(teams)
-	isOdd := teamCount%2 != 0
-	
-	// For odd numbers, we need to handle scheduling differently
-	if isOdd {
-		// Create a round-robin schedule where each team plays every other team twice
-		// One team gets a bye each week
-		
-		// Calculate total weeks needed
-		totalWeeks := teamCount * (teamCount - 1) / ((teamCount - 1) / 2)
-		
-		// Create fixtures week by week
-		for week := 1; week <= totalWeeks; week++ {
-			var weekMatches []models.Match
-			teamsUsedThisWeek := make(map[string]bool)
-			
-			// Determine which team gets the bye this week
-			// Rotate through teams for bye
-			byeTeamIndex := (week - 1) % teamCount
-			byeTeam := teams[byeTeamIndex].Name
-			teamsUsedThisWeek[byeTeam] = true // Mark bye team as used
-			
-			// Create matches for the remaining teams
-			matchesThisWeek := 0
-			maxMatchesPerWeek := (teamCount - 1) / 2
-			
-			// Try to create matches between teams that haven't played this week
-			for i := 0; i < teamCount && matchesThisWeek < maxMatchesPerWeek; i++ {
-				for j := i + 1; j < teamCount && matchesThisWeek < maxMatchesPerWeek; j++ {
-					team1 := teams[i].Name
-					team2 := teams[j].Name
-					
-					// Skip if either team is the bye team or already used
-					if team1 == byeTeam || team2 == byeTeam || 
-					   teamsUsedThisWeek[team1] || teamsUsedThisWeek[team2] {
-						continue
-					}
-					
-					// Create match
-					match := models.Match{
-						HomeTeam: team1,
-						AwayTeam: team2,
-						Week:     week,
-					}
-					weekMatches = append(weekMatches, match)
-					teamsUsedThisWeek[team1] = true
-					teamsUsedThisWeek[team2] = true
-					matchesThisWeek++
-				}
+
+	// Work with team names. An odd count gets an empty bye slot, which takes
+	// the fixed position of the circle so every team rotates.
+	slots := make([]string, 0, len(teams)+1)
+	if len(teams)%2 != 0 {
+		slots = append(slots, "")
+	}
+	for _, team := range teams {
+		slots = append(slots, team.Name)
+	}
+
+	slotCount := len(slots)
+	roundsPerLeg := slotCount - 1
+
+	// order holds the slot of every rotating team, which never changes and
+	// decides the venue of the matches between rotating teams
+	order := make([]int, slotCount)
+	for i := range order {
+		order[i] = i
+	}
+	var firstLeg []Round
+
+	for round := 0; round < roundsPerLeg; round++ {
+		current := Round{Week: round + 1}
+
+		for i := 0; i < slotCount/2; i++ {
+			slot1 := order[i]
+			slot2 := order[slotCount-1-i]
+			team1, team2 := slots[slot1], slots[slot2]
+
+			// The bye slot marks the team that rests this round
+			if team1 == "" || team2 == "" {
+				current.Bye = team1 + team2
+				continue
 			}
-			
-			// Add week to fixtures if it has matches
-			if len(weekMatches) > 0 {
-				fixtures = append(fixtures, weekMatches)
+
+			homeTeam, awayTeam := team1, team2
+			if i == 0 {
+				// The fixed team alternates between home and away
+				if round%2 != 0 {
+					homeTeam, awayTeam = team2, team1
+				}
+			} else if !hostsRotating(slot1, slot2, roundsPerLeg) {
+				homeTeam, awayTeam = team2, team1
 			}
+
+			current.Matches = append(current.Matches, models.Match{
+				HomeTeam: homeTeam,
+				AwayTeam: awayTeam,
+				Week:     current.Week,
+			})
 		}
-		
-		return fixtures
-	}
-	
-	// Even number of teams - use round-robin algorithm
-	// For even teams, we can use a simple round-robin schedule
-	// Each team plays every other team twice (home and away)
-	
-	// Calculate total weeks needed
-	totalWeeks := teamCount * (teamCount - 1) / (teamCount / 2)
-	
-	// Create a round-robin schedule
-	for week := 1; week <= totalWeeks; week++ {
-		var weekMatches []models.Match
-		teamsUsedThisWeek := make(map[string]bool)
-		
-		// For even teams, we can schedule all teams in pairs
-		matchesThisWeek := 0
-		maxMatchesPerWeek := teamCount / 2
-		
-		// Create matches for this week
-		for i := 0; i < teamCount && matchesThisWeek < maxMatchesPerWeek; i++ {
-			for j := i + 1; j < teamCount && matchesThisWeek < maxMatchesPerWeek; j++ {
-				team1 := teams[i].Name
-				team2 := teams[j].Name
-				
-				// Skip if either team is already used this week
-				if teamsUsedThisWeek[team1] || teamsUsedThisWeek[team2] {
-					continue
-				}
-				
-				// Determine home/away based on week number for variety
-				var homeTeam, awayTeam string
-				if (week + i + j) % 2 == 0 {
-					homeTeam, awayTeam = team1, team2
-				} else {
-					homeTeam, awayTeam = team2, team1
+
+		firstLeg = append(firstLeg, current)
+
+		// Rotate every slot except the first one clockwise
+		last := order[slotCount-1]
+		copy(order[2:], order[1:slotCount-1])
+		order[1] = last
+	}
+
+	// Every other leg mirrors the first with home and away swapped
+	var rounds []Round
+	for leg := 0; leg < legs; leg++ {
+		for _, round := range firstLeg {
+			legRound := Round{Week: round.Week + leg*roundsPerLeg, Bye: round.Bye}
+			for _, match := range round.Matches {
+				homeTeam, awayTeam := match.HomeTeam, match.AwayTeam
+				if leg%2 != 0 {
+					homeTeam, awayTeam = awayTeam, homeTeam
 				}
-				
-				// Create match
-				match := models.Match{
+				legRound.Matches = append(legRound.Matches, models.Match{
 					HomeTeam: homeTeam,
 					AwayTeam: awayTeam,
-					Week:     week,
-				}
-				weekMatches = append(weekMatches, match)
-				teamsUsedThisWeek[homeTeam] = true
-				teamsUsedThisWeek[awayTeam] = true
-				matchesThisWeek++
+					Week:     legRound.Week,
+				})
 			}
-		}
-		
-		// Add week to fixtures if it has matches
-		if len(weekMatches) > 0 {
-			fixtures = append(fixtures, weekMatches)
+			rounds = append(rounds, legRound)
 		}
 	}
-	
-	return fixtures
+
+	return rounds
+}
+
+// hostsRotating reports whether the rotating team in slot a hosts the one in
+// slot b. The rotating teams sit on a circle of rotating places (an odd
+// number); a team hosts the ones up to half way round the circle ahead of it
+// and visits the rest, so each hosts exactly half of its rotating opponents.
+func hostsRotating(a, b, rotating int) bool {
+	ahead := ((b-a)%rotating + rotating) % rotating
+	return ahead >= 1 && ahead <= rotating/2
 }
 
+// GenerateFixture returns the matches of a double round-robin schedule grouped by week
+func GenerateFixture(teams []models.Team) [][]models.Match {
+	return GenerateFixtureWithLegs(teams, DoubleRoundRobin)
+}
+
+// GenerateFixtureWithLegs returns the matches of a round robin with the given number of legs grouped by week
+func GenerateFixtureWithLegs(teams []models.Team, legs int) [][]models.Match {
+	var fixtures [][]models.Match
+	for _, round := range GenerateRoundsWithLegs(teams, legs) {
+		fixtures = append(fixtures, round.Matches)
+	}
+	return fixtures
+}
 
-// ValidateWeek ensures that each week has the correct number of teams playing
+// ValidateWeek ensures that each week has the correct number of teams playing.
+// With an odd number of teams exactly one team has a bye.
 func ValidateWeek(week []models.Match, totalTeams int) bool {
 	if len(week) != totalTeams/2 {
 		return false
 	}
-	
+
 	// Check that each team plays exactly once in this week
 	teamsInWeek := make(map[string]bool)
 	for _, match := range week {
+		if match.HomeTeam == match.AwayTeam {
+			return false // Team cannot play itself
+		}
 		if teamsInWeek[match.HomeTeam] || teamsInWeek[match.AwayTeam] {
 			return false // Team already playing in this week
 		}
 		teamsInWeek[match.HomeTeam] = true
 		teamsInWeek[match.AwayTeam] = true
 	}
-	
-	return len(teamsInWeek) == totalTeams
+
+	return len(teamsInWeek) == totalTeams-totalTeams%2
 }
 
+// ValidateSeason checks a whole round-robin schedule: every week must be
+// valid and only league teams may appear. In a single round robin every pair
+// meets once and no team has more than one home or away match over the
+// other; otherwise every pair meets legs/2 times at each venue.
+func ValidateSeason(fixtures [][]models.Match, teams []models.Team, legs int) error {
+	teamCount := len(teams)
+	if teamCount < 2 {
+		return errors.New("at least 2 teams are required")
+	}
+	if !ValidLegs(legs) {
+		return fmt.Errorf("unsupported number of legs: %d", legs)
+	}
 
+	expectedWeeks := legs * (teamCount - 1 + teamCount%2)
+	if len(fixtures) != expectedWeeks {
+		return fmt.Errorf("expected %d weeks, got %d", expectedWeeks, len(fixtures))
+	}
 
+	known := make(map[string]bool)
+	for _, team := range teams {
+		known[team.Name] = true
+	}
 
-func NewGenerateLeague(teams []models.Team)*GenerateLeague {
+	meetings := make(map[[2]string]int)
+	for weekIndex, week := range fixtures {
+		if !ValidateWeek(week, teamCount) {
+			return fmt.Errorf("week %d does not pair every team exactly once", weekIndex+1)
+		}
+		for _, match := range week {
+			if !known[match.HomeTeam] || !known[match.AwayTeam] {
+				return fmt.Errorf("week %d contains an unknown team", weekIndex+1)
+			}
+			meetings[[2]string{match.HomeTeam, match.AwayTeam}]++
+		}
+	}
+
+	// Within a single round robin no team may host more than one match
+	// beyond those it plays away, or the other way round
+	if legs == SingleRoundRobin {
+		for _, team := range teams {
+			hosted, visited := 0, 0
+			for pair, count := range meetings {
+				if pair[0] == team.Name {
+					hosted += count
+				}
+				if pair[1] == team.Name {
+					visited += count
+				}
+			}
+			if hosted-visited > 1 || visited-hosted > 1 {
+				return fmt.Errorf("%s plays %d home and %d away matches", team.Name, hosted, visited)
+			}
+		}
+	}
+
+	for i, home := range teams {
+		for j, away := range teams {
+			if i == j {
+				continue
+			}
+			hosted := meetings[[2]string{home.Name, away.Name}]
+
+			// A single round robin only needs one meeting, at either venue
+			if legs == SingleRoundRobin {
+				if i < j {
+					if total := hosted + meetings[[2]string{away.Name, home.Name}]; total != 1 {
+						return fmt.Errorf("%s and %s meet %d times, expected once", home.Name, away.Name, total)
+					}
+				}
+				continue
+			}
+
+			// Each ordered (home, away) pair must appear legs/2 times
+			if hosted != legs/2 {
+				return fmt.Errorf("%s hosts %s %d times, expected %d", home.Name, away.Name, hosted, legs/2)
+			}
+		}
+	}
+
+	return nil
+}
+
+// NewGenerateLeague creates a league seeded from the current time
+func NewGenerateLeague(teams []models.Team) *GenerateLeague {
+	return NewSeededLeague(teams, time.Now().UnixNano())
+}
+
+// NewSeededLeague creates a league whose simulations are fully determined by seed
+func NewSeededLeague(teams []models.Team, seed int64) *GenerateLeague {
 	engine := GenerateLeague{
-		Teams: teams,
-		Fixtures: GenerateFixture(teams),
-		CurrentWeek: 0,
-		TeamStats: make(map[string]*TeamStats),
+		Teams:        teams,
+		Fixtures:     GenerateFixture(teams),
+		CurrentWeek:  0,
+		TeamStats:    make(map[string]*TeamStats),
+		Seed:         seed,
+		Engine:       engines[DefaultEngineName],
+		Params:       DefaultSimulationParams,
+		Rules:        DefaultRules(),
+		RatingSystem: RatingsNone,
 	}
+	engine.ReseedForWeek(1)
 
 	// Initialize TeamStats for all teams
 	for _, team := range teams {
 		engine.TeamStats[team.Name] = &TeamStats{
-			TeamName: team.Name,
-			Played: 0,
-			Won: 0,
-			Drawn: 0,
-			Lost: 0,
-			GoalsFor: 0,
+			TeamName:     team.Name,
+			Played:       0,
+			Won:          0,
+			Drawn:        0,
+			Lost:         0,
+			GoalsFor:     0,
 			GoalsAgainst: 0,
-			Points: 0,
-			GoalDiff: 0,
+			Points:       0,
+			GoalDiff:     0,
 		}
 	}
 	return &engine
 }
 
-func(l *GenerateLeague) PlayWeek() error {
-	if l.CurrentWeek >= len(l.Fixtures){
+// ReseedForWeek resets the league's random source for the given week. The
+// source only depends on the league seed and the week number, so a week
+// replays identically even when the league is rebuilt from storage.
+func (l *GenerateLeague) ReseedForWeek(week int) {
+	l.rng = rand.New(rand.NewSource(WeekSeed(l.Seed, week)))
+	l.cardRng = rand.New(rand.NewSource(WeekSeed(l.Seed, week) ^ cardSalt))
+}
+
+// WeekSeed derives the random seed used for a single week of a league
+func WeekSeed(seed int64, week int) int64 {
+	return seed*1000003 + int64(week)
+}
+
+func (l *GenerateLeague) PlayWeek() error {
+	if l.CurrentWeek >= len(l.Fixtures) {
 		return errors.New("End of season, no more matches to play.")
 	}
 
+	l.ReseedForWeek(l.CurrentWeek + 1)
+
 	currentMatches := l.Fixtures[l.CurrentWeek]
 
-	for i := range currentMatches{
+	for i := range currentMatches {
 		homeTeamName := currentMatches[i].HomeTeam
-		awayTeamName := currentMatches[i].AwayTeam 
+		awayTeamName := currentMatches[i].AwayTeam
 
 		// Find the actual team objects
 		var homeTeam, awayTeam models.Team
@@ -235,254 +395,219 @@ func(l *GenerateLeague) PlayWeek() error {
 		if err != nil {
 			return err
 		}
+		// PlayMatch has already updated the league table
 		l.Results = append(l.Results, match)
-		
-		// Update league table after each match
-		l.updateLeagueTable(match)
 	}
 
 	l.CurrentWeek++
 	return nil
 }
 
+// PlayMatch plays a match with the league's engine and updates the league table
 func PlayMatch(homeTeam, awayTeam models.Team, league *GenerateLeague) (models.Match, error) {
+	engine := league.Engine
+	if engine == nil {
+		var err error
+		if engine, err = GetEngine(DefaultEngineName); err != nil {
+			return models.Match{}, err
+		}
+	}
+
+	homeScore, awayScore := engine.Score(homeTeam, awayTeam, league)
+
+	match := models.Match{
+		HomeTeam:  homeTeam.Name,
+		AwayTeam:  awayTeam.Name,
+		HomeScore: homeScore,
+		AwayScore: awayScore,
+		Cards:     DefaultCardRates.sampleCards(league.cardRng),
+	}
+
+	// Update league table with the match result
+	league.updateLeagueTable(match)
+
+	return match, nil
+}
+
+// classicScore is the original scoring logic: it first decides whether the
+// match is drawn, and otherwise picks a winner from the strength ratio and
+// builds both scores with generateScore
+func classicScore(homeTeam, awayTeam models.Team, league *GenerateLeague) (int, int) {
 	// Calculate dynamic strengths based on form and home advantage
 	homeStrength := calculateTeamStrength(homeTeam, league, true)  // true = home team
 	awayStrength := calculateTeamStrength(awayTeam, league, false) // false = away team
 
+	// Draw chance, more likely if teams are close in strength
+	strengthDifference := math.Abs(homeStrength - awayStrength)
+	// Base draw chance of 25%, exponentially decreases with strength difference
+	drawChance := 0.25 * math.Exp(-strengthDifference/50)
+	if drawChance < 0.05 {
+		drawChance = 0.05 // Minimum 5%
+	}
+
+	if league.rng.Float64() < drawChance {
+		// Draw - both teams score the same
+		drawScore := generateDrawScore(league.rng, (homeStrength+awayStrength)/2)
+		return drawScore, drawScore
+	}
+
 	// Calculate win probability based on adjusted strengths
 	totalStrength := homeStrength + awayStrength
 	homeWinProb := homeStrength / totalStrength
-	
-	// Generate random outcome
-	randomResult := rand.Float64()
-	
+
 	var homeScore, awayScore int
-	
+
 	// Determine match result based on adjusted team strength
-	if randomResult < homeWinProb {
+	if league.rng.Float64() < homeWinProb {
 		// Home team wins - score based on strength difference
 		strengthDiff := homeStrength - awayStrength
-		homeScore = generateScore(homeStrength, strengthDiff, true)
-		awayScore = generateScore(awayStrength, -strengthDiff, false)
+		homeScore = generateScore(league.rng, homeStrength, strengthDiff, true)
+		awayScore = generateScore(league.rng, awayStrength, -strengthDiff, false)
 	} else {
 		// Away team wins - score based on strength difference
 		strengthDiff := awayStrength - homeStrength
-		awayScore = generateScore(awayStrength, strengthDiff, true)
-		homeScore = generateScore(homeStrength, -strengthDiff, false)
-	}
-	
-	// Handle potential draw (small chance, more likely if teams are close in strength)
-	strengthDifference := math.Abs(homeStrength - awayStrength)
-	// Base draw chance of 25%, exponentially decreases with strength difference
-	drawChance := 0.25 * math.Exp(-strengthDifference/50)
-	if drawChance < 0.05 {
-		drawChance = 0.05 // Minimum 5%
-	}
-	
-	if rand.Float64() < drawChance {
-		// Draw - both teams score similar amounts
-		avgStrength := (homeStrength + awayStrength) / 2
-		drawScore := generateDrawScore(avgStrength)
-		homeScore = drawScore
-		awayScore = drawScore
+		awayScore = generateScore(league.rng, awayStrength, strengthDiff, true)
+		homeScore = generateScore(league.rng, homeStrength, -strengthDiff, false)
 	}
-	
-	match := models.Match{
-		HomeTeam: homeTeam.Name,
-		AwayTeam: awayTeam.Name,
-		HomeScore: homeScore,
-		AwayScore: awayScore,
-	}
-	
-	// Update league table with the match result
-	league.updateLeagueTable(match)
-	
-	return match, nil
+
+	return homeScore, awayScore
 }
 
 // calculateTeamStrength calculates dynamic team strength based on form and home advantage
 func calculateTeamStrength(team models.Team, league *GenerateLeague, isHome bool) float64 {
 	baseStrength := float64(team.Strength)
-	
-	// Home advantage (3% boost)
+	if league.SimulateWithRatings && league.RatingSystem == RatingsElo {
+		baseStrength = RatingStrength(league.Rating(team.Name))
+	}
+
+	// Home advantage
 	if isHome {
-		baseStrength *= 1.03
+		baseStrength *= league.Params.HomeAdvantage
 	}
-	
-	// Form factor based on recent results
-	formBonus := calculateFormBonus(team.Name, league)
-	baseStrength *= (1.0 + formBonus)
-	
+
+	// Form factor based on the recent results in the form window
+	baseStrength *= 1.0 + league.Params.FormBonus*league.Form(team.Name).Rating
+
 	return baseStrength
 }
 
-// calculateFormBonus calculates bonus based on the most recent match result
-func calculateFormBonus(teamName string, league *GenerateLeague) float64 {
-	lastResult := getLastResult(teamName, league)
-	
-	switch lastResult {
-	case "W":
-		return 0.05 // 5% boost after a win
-	case "L":
-		return -0.05 // 5% penalty after a loss
-	case "D":
-		return 0.0 // No effect after a draw
-	default:
-		return 0.0 // No recent matches
+// generateScore generates realistic score based on team strength and strength difference
+func generateScore(rng *rand.Rand, teamStrength, strengthDiff float64, isWinner bool) int {
+	// Base from team strength
+	baseGoals := int(teamStrength / 32)
+
+	// Exponential impact of strength difference
+	// Small differences have minimal impact, large differences have big impact
+	diffMultiplier := 1.0 + (strengthDiff/100)*0.5
+	if diffMultiplier < 0.3 {
+		diffMultiplier = 0.3 // Minimum 30% of base
 	}
-}
 
+	baseGoals = int(float64(baseGoals) * diffMultiplier)
 
+	// Add randomness
+	totalGoals := baseGoals + rng.Intn(2)
 
-// generateScore generates realistic score based on team strength and strength difference
-func generateScore(teamStrength, strengthDiff float64, isWinner bool) int {
-    // Base from team strength
-    baseGoals := int(teamStrength / 32)
-    
-    // Exponential impact of strength difference
-    // Small differences have minimal impact, large differences have big impact
-    diffMultiplier := 1.0 + (strengthDiff / 100) * 0.5
-    if diffMultiplier < 0.3 {
-        diffMultiplier = 0.3 // Minimum 30% of base
-    }
-    
-    baseGoals = int(float64(baseGoals) * diffMultiplier)
-    
-    // Add randomness
-    totalGoals := baseGoals + rand.Intn(2)
-    
-    // Range check
-    if totalGoals < 0 {
-        totalGoals = 0
-    } else if totalGoals > 5 {
-        totalGoals = 5
-    }
-    
-    return totalGoals
-}
-
-// generateDrawScore generates score for a draw
-func generateDrawScore(avgStrength float64) int {
-	baseGoals := int(avgStrength / 25)
-	randomGoals := rand.Intn(1)
-	totalGoals := baseGoals + randomGoals
-	
-	// Ensure realistic draw score
+	// Range check
 	if totalGoals < 0 {
 		totalGoals = 0
-	} else if totalGoals > 2 {
-		totalGoals = 2
+	} else if totalGoals > 5 {
+		totalGoals = 5
 	}
+
 	return totalGoals
 }
 
-// getLastResult gets the most recent match result for a team
-func getLastResult(teamName string, league *GenerateLeague) string {
-	// Go through results in reverse order to find the most recent match
-	for i := len(league.Results) - 1; i >= 0; i-- {
-		match := league.Results[i]
-		
-		if match.HomeTeam == teamName {
-			if match.HomeScore > match.AwayScore {
-				return "W"
-			} else if match.HomeScore == match.AwayScore {
-				return "D"
-			} else {
-				return "L"
-			}
-		} else if match.AwayTeam == teamName {
-			if match.AwayScore > match.HomeScore {
-				return "W"
-			} else if match.AwayScore == match.HomeScore {
-				return "D"
-			} else {
-				return "L"
-			}
+// maxDrawGoals is the highest score either team gets in a classic draw
+const maxDrawGoals = 2
+
+// generateDrawScore generates the score of each team in a draw, between 0 and
+// a limit that grows with the teams' average strength
+func generateDrawScore(rng *rand.Rand, avgStrength float64) int {
+	maxGoals := min(int(avgStrength/25), maxDrawGoals)
+	if maxGoals < 0 {
+		maxGoals = 0
+	}
+	return rng.Intn(maxGoals + 1)
+}
+
+// ApplyResults records already played matches, updating results and the league table
+func (l *GenerateLeague) ApplyResults(matches []models.Match) {
+	for _, match := range matches {
+		if _, exists := l.TeamStats[match.HomeTeam]; !exists {
+			continue
 		}
+		if _, exists := l.TeamStats[match.AwayTeam]; !exists {
+			continue
+		}
+		l.Results = append(l.Results, match)
+		l.updateLeagueTable(match)
 	}
-	
-	return "" // No previous matches found
 }
 
 // updateLeagueTable updates team statistics after a match
 func (l *GenerateLeague) updateLeagueTable(match models.Match) {
 	homeStats := l.TeamStats[match.HomeTeam]
 	awayStats := l.TeamStats[match.AwayTeam]
-	
+
 	// Update goals
 	homeStats.GoalsFor += match.HomeScore
 	homeStats.GoalsAgainst += match.AwayScore
 	awayStats.GoalsFor += match.AwayScore
 	awayStats.GoalsAgainst += match.HomeScore
-	
+
 	// Update matches played
 	homeStats.Played++
 	awayStats.Played++
-	
-	// Update results and points based on Premier League rules
+
+	// Update results
 	if match.HomeScore > match.AwayScore {
-		// Home team wins
 		homeStats.Won++
 		awayStats.Lost++
-		homeStats.Points += 3 // 3 points for win
-		awayStats.Points += 0 // 0 points for loss
 	} else if match.HomeScore < match.AwayScore {
-		// Away team wins
 		awayStats.Won++
 		homeStats.Lost++
-		awayStats.Points += 3 // 3 points for win
-		homeStats.Points += 0 // 0 points for loss
 	} else {
-		// Draw
 		homeStats.Drawn++
 		awayStats.Drawn++
-		homeStats.Points += 1 // 1 point for draw
-		awayStats.Points += 1 // 1 point for draw
 	}
-	
+
+	// Award points according to the league rules
+	homeStats.Points += MatchPoints(l.Rules, match.HomeScore, match.AwayScore)
+	awayStats.Points += MatchPoints(l.Rules, match.AwayScore, match.HomeScore)
+
 	// Update goal difference
 	homeStats.GoalDiff = homeStats.GoalsFor - homeStats.GoalsAgainst
 	awayStats.GoalDiff = awayStats.GoalsFor - awayStats.GoalsAgainst
+
+	// Recent results feed the form and the ratings of both teams
+	l.recordForm(match)
+	l.recordRating(match)
 }
 
-// GetLeagueTable returns the current league standings sorted by points, goal difference, and goals scored
+// GetLeagueTable returns the current league standings sorted by points and the tie-breakers of the league rules
 func (l *GenerateLeague) GetLeagueTable() []*TeamStats {
 	var standings []*TeamStats
-	
+
 	// Convert map to slice
 	for _, stats := range l.TeamStats {
 		standings = append(standings, stats)
 	}
-	
-	// Sort by Premier League rules: Points (desc), Goal Difference (desc), Goals For (desc)
-	sort.Slice(standings, func(i, j int) bool {
-		// First by points (descending)
-		if standings[i].Points != standings[j].Points {
-			return standings[i].Points > standings[j].Points
-		}
-		// Then by goal difference (descending)
-		if standings[i].GoalDiff != standings[j].GoalDiff {
-			return standings[i].GoalDiff > standings[j].GoalDiff
-		}
-		// Finally by goals scored (descending)
-		return standings[i].GoalsFor > standings[j].GoalsFor
-	})
-	
+
+	RankStandings(standings, l.Results, l.Rules, l.Seed)
+
 	return standings
 }
 
 // GetTeamPosition returns the current league position of a team (1-based)
 func (l *GenerateLeague) GetTeamPosition(teamName string) int {
 	standings := l.GetLeagueTable()
-	
+
 	for i, stats := range standings {
 		if stats.TeamName == teamName {
 			return i + 1 // Return 1-based position
 		}
 	}
-	
+
 	return 0 // Team not found
 }
-
-
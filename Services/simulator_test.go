@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"insider-league/Models"
+	"math/rand"
+	"testing"
+)
+
+// testTeams returns count teams named T1, T2, ...
+func testTeams(count int) []models.Team {
+	teams := make([]models.Team, count)
+	for i := range teams {
+		teams[i] = models.Team{ID: i + 1, Name: fmt.Sprintf("T%d", i+1), Strength: 50 + i}
+	}
+	return teams
+}
+
+func TestGenerateRoundsWithLegs(t *testing.T) {
+	for teamCount := 2; teamCount <= 12; teamCount++ {
+		for _, legs := range []int{SingleRoundRobin, DoubleRoundRobin, QuadrupleRoundRobin} {
+			t.Run(fmt.Sprintf("%d teams %d legs", teamCount, legs), func(t *testing.T) {
+				teams := testTeams(teamCount)
+				rounds := GenerateRoundsWithLegs(teams, legs)
+				fixtures := GenerateFixtureWithLegs(teams, legs)
+
+				if err := ValidateSeason(fixtures, teams, legs); err != nil {
+					t.Fatalf("ValidateSeason: %v", err)
+				}
+
+				// Odd counts rest every team exactly once per leg
+				byes := make(map[string]int)
+				for _, round := range rounds {
+					if round.Bye != "" {
+						byes[round.Bye]++
+					}
+				}
+				for _, team := range teams {
+					want := 0
+					if teamCount%2 != 0 {
+						want = legs
+					}
+					if byes[team.Name] != want {
+						t.Errorf("%s has %d byes, want %d", team.Name, byes[team.Name], want)
+					}
+				}
+
+				// Within every leg each team hosts half its matches, give or
+				// take one when it plays an odd number of them
+				roundsPerLeg := len(rounds) / legs
+				for leg := 0; leg < legs; leg++ {
+					home := make(map[string]int)
+					away := make(map[string]int)
+					for _, round := range rounds[leg*roundsPerLeg : (leg+1)*roundsPerLeg] {
+						for _, match := range round.Matches {
+							home[match.HomeTeam]++
+							away[match.AwayTeam]++
+						}
+					}
+					for _, team := range teams {
+						diff := home[team.Name] - away[team.Name]
+						if teamCount%2 != 0 && diff != 0 {
+							t.Errorf("leg %d: %s plays %d home and %d away", leg+1, team.Name, home[team.Name], away[team.Name])
+						}
+						if diff > 1 || diff < -1 {
+							t.Errorf("leg %d: %s plays %d home and %d away", leg+1, team.Name, home[team.Name], away[team.Name])
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestGenerateRoundsWithLegsRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		teams int
+		legs  int
+	}{
+		{"one team", 1, DoubleRoundRobin},
+		{"no teams", 0, SingleRoundRobin},
+		{"three legs", 4, 3},
+		{"zero legs", 4, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if rounds := GenerateRoundsWithLegs(testTeams(test.teams), test.legs); len(rounds) != 0 {
+				t.Errorf("got %d rounds, want none", len(rounds))
+			}
+		})
+	}
+}
+
+func TestValidateSeason(t *testing.T) {
+	teams := testTeams(3)
+	match := func(home, away string) models.Match {
+		return models.Match{HomeTeam: home, AwayTeam: away}
+	}
+
+	tests := []struct {
+		name     string
+		fixtures [][]models.Match
+		legs     int
+		wantErr  bool
+	}{
+		{
+			name:     "balanced single round robin",
+			fixtures: [][]models.Match{{match("T1", "T2")}, {match("T2", "T3")}, {match("T3", "T1")}},
+			legs:     SingleRoundRobin,
+		},
+		{
+			name:     "team never at home",
+			fixtures: [][]models.Match{{match("T2", "T1")}, {match("T2", "T3")}, {match("T3", "T1")}},
+			legs:     SingleRoundRobin,
+			wantErr:  true,
+		},
+		{
+			name:     "pair meets twice",
+			fixtures: [][]models.Match{{match("T1", "T2")}, {match("T2", "T1")}, {match("T3", "T1")}},
+			legs:     SingleRoundRobin,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown team",
+			fixtures: [][]models.Match{{match("T1", "T9")}, {match("T2", "T3")}, {match("T3", "T1")}},
+			legs:     SingleRoundRobin,
+			wantErr:  true,
+		},
+		{
+			name:     "missing week",
+			fixtures: [][]models.Match{{match("T1", "T2")}, {match("T2", "T3")}},
+			legs:     SingleRoundRobin,
+			wantErr:  true,
+		},
+		{
+			name: "second leg not mirrored",
+			fixtures: [][]models.Match{
+				{match("T1", "T2")}, {match("T2", "T3")}, {match("T3", "T1")},
+				{match("T1", "T2")}, {match("T3", "T2")}, {match("T1", "T3")},
+			},
+			legs:    DoubleRoundRobin,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateSeason(test.fixtures, teams, test.legs)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ValidateSeason() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateDrawScore(t *testing.T) {
+	tests := []struct {
+		avgStrength float64
+		want        []int
+	}{
+		{avgStrength: 10, want: []int{0}},
+		{avgStrength: 30, want: []int{0, 1}},
+		{avgStrength: 90, want: []int{0, 1, 2}},
+	}
+
+	for _, test := range tests {
+		rng := rand.New(rand.NewSource(1))
+		seen := make(map[int]bool)
+		for i := 0; i < 200; i++ {
+			seen[generateDrawScore(rng, test.avgStrength)] = true
+		}
+		if len(seen) != len(test.want) {
+			t.Errorf("strength %.0f: draw scores %v, want %v", test.avgStrength, seen, test.want)
+		}
+		for _, score := range test.want {
+			if !seen[score] {
+				t.Errorf("strength %.0f: draw score %d never generated", test.avgStrength, score)
+			}
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the server settings. Each value comes from, in increasing
+// order of precedence: the defaults below, the optional JSON config file,
+// environment variables and command-line flags.
+type Config struct {
+	ListenAddr     string           `json:"listen_addr"`
+	AllowedOrigins []string         `json:"allowed_origins"`
+	APIBase        string           `json:"api_base"`
+	Memory         bool             `json:"memory"`
+	Seed           bool             `json:"seed"`
+	Server         ServerConfig     `json:"server"`
+	Database       DatabaseConfig   `json:"database"`
+	Simulation     SimulationConfig `json:"simulation"`
+}
+
+// ServerConfig holds the HTTP server timeouts; zero means no limit
+type ServerConfig struct {
+	ReadTimeout     Duration `json:"read_timeout"`
+	WriteTimeout    Duration `json:"write_timeout"`
+	IdleTimeout     Duration `json:"idle_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+}
+
+// DatabaseConfig holds the PostgreSQL connection settings
+type DatabaseConfig struct {
+	URL             string   `json:"url"`
+	MaxOpenConns    int      `json:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime"`
+}
+
+// SimulationConfig holds the simulation parameters given to new leagues
+type SimulationConfig struct {
+	HomeAdvantage float64 `json:"home_advantage"`
+	FormBonus     float64 `json:"form_bonus"`
+	FormWindow    int     `json:"form_window"`
+	FormDecay     float64 `json:"form_decay"`
+
+	EloKFactor       float64 `json:"elo_k_factor"`
+	EloHomeAdvantage float64 `json:"elo_home_advantage"`
+}
+
+// Duration is a time.Duration written as a string such as "30m" in the config file
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30m\"")
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Default returns the settings used when nothing else is configured
+func Default() Config {
+	return Config{
+		ListenAddr:     ":8080",
+		AllowedOrigins: []string{"*"},
+		APIBase:        "/api",
+		Server: ServerConfig{
+			ReadTimeout:     Duration{15 * time.Second},
+			WriteTimeout:    Duration{60 * time.Second},
+			IdleTimeout:     Duration{120 * time.Second},
+			ShutdownTimeout: Duration{30 * time.Second},
+		},
+		Database: DatabaseConfig{
+			URL:             "host=localhost port=5433 user=iremsuozdemir dbname=football_league sslmode=disable",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration{30 * time.Minute},
+		},
+		Simulation: SimulationConfig{
+			HomeAdvantage: 1.03,
+			FormBonus:     0.05,
+			FormWindow:    5,
+			FormDecay:     0.8,
+
+			EloKFactor:       20,
+			EloHomeAdvantage: 60,
+		},
+	}
+}
+
+// Load builds the configuration from the command-line arguments (without
+// the program name), the environment and the config file named by -config
+// or LEAGUE_CONFIG
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("insider-league", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("LEAGUE_CONFIG"), "path to a JSON config file")
+	addr := fs.String("addr", "", "address to listen on, for example :8080")
+	databaseURL := fs.String("database-url", "", "PostgreSQL connection string")
+	origins := fs.String("cors-origins", "", "comma-separated origins allowed to call the API, * for any")
+	apiBase := fs.String("api-base", "", "API base URL given to the web interface, /api when it is served by this server")
+	readTimeout := fs.Duration("read-timeout", 0, "maximum time to read a request, 0 for no limit")
+	writeTimeout := fs.Duration("write-timeout", 0, "maximum time to handle a request and write the response, 0 for no limit")
+	idleTimeout := fs.Duration("idle-timeout", 0, "how long idle keep-alive connections are kept open")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 0, "how long to wait for requests to finish on shutdown")
+	maxOpen := fs.Int("db-max-open-conns", 0, "maximum number of open database connections")
+	maxIdle := fs.Int("db-max-idle-conns", 0, "maximum number of idle database connections")
+	maxLifetime := fs.Duration("db-conn-max-lifetime", 0, "maximum time a database connection is reused")
+	homeAdvantage := fs.Float64("home-advantage", 0, "strength multiplier for the home team")
+	formBonus := fs.Float64("form-bonus", 0, "strength change at the best or worst form")
+	formWindow := fs.Int("form-window", 0, "number of recent matches form looks back over")
+	formDecay := fs.Float64("form-decay", 0, "weight of each match in the form window relative to the one after it")
+	eloKFactor := fs.Float64("elo-k-factor", 0, "Elo rating points at stake in a one-goal match")
+	eloHomeAdvantage := fs.Float64("elo-home-advantage", 0, "Elo rating points added to the home team")
+	memory := fs.Bool("memory", false, "keep all data in memory instead of PostgreSQL")
+	seed := fs.Bool("seed", false, "add the sample teams to the database at startup")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return cfg, err
+	}
+
+	// Only flags given on the command line override the other sources
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.ListenAddr = *addr
+		case "database-url":
+			cfg.Database.URL = *databaseURL
+		case "cors-origins":
+			cfg.AllowedOrigins = splitList(*origins)
+		case "api-base":
+			cfg.APIBase = *apiBase
+		case "read-timeout":
+			cfg.Server.ReadTimeout = Duration{*readTimeout}
+		case "write-timeout":
+			cfg.Server.WriteTimeout = Duration{*writeTimeout}
+		case "idle-timeout":
+			cfg.Server.IdleTimeout = Duration{*idleTimeout}
+		case "shutdown-timeout":
+			cfg.Server.ShutdownTimeout = Duration{*shutdownTimeout}
+		case "db-max-open-conns":
+			cfg.Database.MaxOpenConns = *maxOpen
+		case "db-max-idle-conns":
+			cfg.Database.MaxIdleConns = *maxIdle
+		case "db-conn-max-lifetime":
+			cfg.Database.ConnMaxLifetime = Duration{*maxLifetime}
+		case "home-advantage":
+			cfg.Simulation.HomeAdvantage = *homeAdvantage
+		case "form-bonus":
+			cfg.Simulation.FormBonus = *formBonus
+		case "form-window":
+			cfg.Simulation.FormWindow = *formWindow
+		case "form-decay":
+			cfg.Simulation.FormDecay = *formDecay
+		case "elo-k-factor":
+			cfg.Simulation.EloKFactor = *eloKFactor
+		case "elo-home-advantage":
+			cfg.Simulation.EloHomeAdvantage = *eloHomeAdvantage
+		case "memory":
+			cfg.Memory = *memory
+		case "seed":
+			cfg.Seed = *seed
+		}
+	})
+
+	return cfg, cfg.Validate()
+}
+
+// loadFile overrides the settings present in a JSON config file
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides the settings whose environment variables are set
+func (c *Config) loadEnv() error {
+	if value := os.Getenv("DATABASE_URL"); value != "" {
+		c.Database.URL = value
+	}
+
+	// PORT is set by hosting platforms such as Heroku; LISTEN_ADDR wins over it
+	if value := os.Getenv("PORT"); value != "" {
+		c.ListenAddr = ":" + value
+	}
+	if value := os.Getenv("LISTEN_ADDR"); value != "" {
+		c.ListenAddr = value
+	}
+
+	if value := os.Getenv("CORS_ALLOWED_ORIGINS"); value != "" {
+		c.AllowedOrigins = splitList(value)
+	}
+	if value := os.Getenv("API_BASE"); value != "" {
+		c.APIBase = value
+	}
+
+	var err error
+	if c.Database.MaxOpenConns, err = envInt("DB_MAX_OPEN_CONNS", c.Database.MaxOpenConns); err != nil {
+		return err
+	}
+	if c.Database.MaxIdleConns, err = envInt("DB_MAX_IDLE_CONNS", c.Database.MaxIdleConns); err != nil {
+		return err
+	}
+	if c.Database.ConnMaxLifetime, err = envDuration("DB_CONN_MAX_LIFETIME", c.Database.ConnMaxLifetime); err != nil {
+		return err
+	}
+
+	if c.Server.ReadTimeout, err = envDuration("READ_TIMEOUT", c.Server.ReadTimeout); err != nil {
+		return err
+	}
+	if c.Server.WriteTimeout, err = envDuration("WRITE_TIMEOUT", c.Server.WriteTimeout); err != nil {
+		return err
+	}
+	if c.Server.IdleTimeout, err = envDuration("IDLE_TIMEOUT", c.Server.IdleTimeout); err != nil {
+		return err
+	}
+	if c.Server.ShutdownTimeout, err = envDuration("SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout); err != nil {
+		return err
+	}
+
+	if c.Simulation.HomeAdvantage, err = envFloat("HOME_ADVANTAGE", c.Simulation.HomeAdvantage); err != nil {
+		return err
+	}
+	if c.Simulation.FormBonus, err = envFloat("FORM_BONUS", c.Simulation.FormBonus); err != nil {
+		return err
+	}
+	if c.Simulation.FormWindow, err = envInt("FORM_WINDOW", c.Simulation.FormWindow); err != nil {
+		return err
+	}
+	if c.Simulation.FormDecay, err = envFloat("FORM_DECAY", c.Simulation.FormDecay); err != nil {
+		return err
+	}
+	if c.Simulation.EloKFactor, err = envFloat("ELO_K_FACTOR", c.Simulation.EloKFactor); err != nil {
+		return err
+	}
+	if c.Simulation.EloHomeAdvantage, err = envFloat("ELO_HOME_ADVANTAGE", c.Simulation.EloHomeAdvantage); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks that the settings can be used
+func (c Config) Validate() error {
+	if c.ListenAddr == "" {
+		return fmt.Errorf("listen address must not be empty")
+	}
+	if len(c.AllowedOrigins) == 0 {
+		return fmt.Errorf("at least one allowed CORS origin is required, use * for any")
+	}
+	if c.APIBase == "" {
+		return fmt.Errorf("API base must not be empty")
+	}
+	if !c.Memory && c.Database.URL == "" {
+		return fmt.Errorf("database URL must not be empty")
+	}
+	if c.Server.ReadTimeout.Duration < 0 || c.Server.WriteTimeout.Duration < 0 ||
+		c.Server.IdleTimeout.Duration < 0 || c.Server.ShutdownTimeout.Duration < 0 {
+		return fmt.Errorf("server timeouts must not be negative")
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnMaxLifetime.Duration < 0 {
+		return fmt.Errorf("database pool settings must not be negative")
+	}
+	if c.Simulation.HomeAdvantage < 0.5 || c.Simulation.HomeAdvantage > 2 {
+		return fmt.Errorf("home advantage must be between 0.5 and 2")
+	}
+	if c.Simulation.FormBonus < 0 || c.Simulation.FormBonus >= 1 {
+		return fmt.Errorf("form bonus must be at least 0 and below 1")
+	}
+	if c.Simulation.FormWindow < 1 || c.Simulation.FormWindow > 38 {
+		return fmt.Errorf("form window must be between 1 and 38 matches")
+	}
+	if c.Simulation.FormDecay <= 0 || c.Simulation.FormDecay > 1 {
+		return fmt.Errorf("form decay must be above 0 and at most 1")
+	}
+	if c.Simulation.EloKFactor <= 0 || c.Simulation.EloKFactor > 100 {
+		return fmt.Errorf("Elo K-factor must be above 0 and at most 100")
+	}
+	if c.Simulation.EloHomeAdvantage < 0 || c.Simulation.EloHomeAdvantage > 400 {
+		return fmt.Errorf("Elo home advantage must be between 0 and 400")
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envInt reads an optional integer environment variable
+func envInt(name string, current int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return current, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return current, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return value, nil
+}
+
+// envFloat reads an optional number environment variable
+func envFloat(name string, current float64) (float64, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return current, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return current, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return value, nil
+}
+
+// envDuration reads an optional duration environment variable such as "30s"
+func envDuration(name string, current Duration) (Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return current, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return current, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return Duration{value}, nil
+}
@@ -3,27 +3,39 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"time"
+
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
+// Options configures the connection to PostgreSQL
+type Options struct {
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
 // Connect establishes a connection to PostgreSQL
-func Connect() error {
-	// Connection string for PostgreSQL 15 on port 5433
-	connectionString := "host=localhost port=5433 user=iremsuozdemir dbname=football_league sslmode=disable"
-	
+func Connect(opts Options) error {
 	var err error
-	DB, err = sql.Open("postgres", connectionString)
+	DB, err = sql.Open("postgres", opts.DSN)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %v", err)
 	}
-	
+
+	// Size the connection pool
+	DB.SetMaxOpenConns(opts.MaxOpenConns)
+	DB.SetMaxIdleConns(opts.MaxIdleConns)
+	DB.SetConnMaxLifetime(opts.ConnMaxLifetime)
+
 	// Test the connection
 	if err = DB.Ping(); err != nil {
 		return fmt.Errorf("failed to ping database: %v", err)
 	}
-	
+
 	fmt.Println("✅ Connected to PostgreSQL database successfully!")
 	return nil
 }
@@ -34,4 +46,4 @@ func Close() error {
 		return DB.Close()
 	}
 	return nil
-} 
\ No newline at end of file
+}
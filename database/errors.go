@@ -0,0 +1,73 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Kinds of repository error. Errors the caller can act on wrap one of these,
+// so handlers tell them apart with errors.Is; any other error means the
+// storage itself failed.
+var (
+	// ErrNotFound is returned when a team, league or match does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a change clashes with the stored data,
+	// such as a duplicate team name
+	ErrConflict = errors.New("conflict")
+	// ErrValidation is returned when a value breaks a storage constraint
+	ErrValidation = errors.New("validation failed")
+	// ErrSeasonComplete is returned when a league has no weeks left to play
+	ErrSeasonComplete = errors.New("season is complete, no more weeks to play")
+)
+
+// Error is a repository error of one of the kinds above. Its message is
+// meant for API clients and does not repeat the kind.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap makes errors.Is match the kind
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// notFoundf returns an ErrNotFound error with a formatted message
+func notFoundf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// conflictf returns an ErrConflict error with a formatted message
+func conflictf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// invalidf returns an ErrValidation error with a formatted message
+func invalidf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+// classify gives PostgreSQL constraint violations a kind: unique and foreign
+// key violations become ErrConflict and the others ErrValidation. Any other
+// error is wrapped as it is.
+func classify(err error, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505", "23503": // unique_violation, foreign_key_violation
+			return &Error{Kind: ErrConflict, Message: fmt.Sprintf("%s: %s", message, pqErr.Message)}
+		case "23502", "23514", "22001", "22003": // not_null, check, string too long, out of range
+			return &Error{Kind: ErrValidation, Message: fmt.Sprintf("%s: %s", message, pqErr.Message)}
+		}
+	}
+
+	return fmt.Errorf("%s: %w", message, err)
+}
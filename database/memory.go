@@ -0,0 +1,944 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"insider-league/Models"
+	"insider-league/Services"
+	"sort"
+	"sync"
+)
+
+// MemoryRepository keeps all data in process memory. It follows the same
+// rules as the PostgreSQL tables, so the server can run without a database.
+// Single operations finish quickly and ignore their context; PlayAllWeeks
+// checks it between weeks.
+type MemoryRepository struct {
+	// mu guards all fields; league operations hold it for their whole run,
+	// like the league row lock taken by TeamRepository
+	mu sync.RWMutex
+
+	teams       map[int]*models.Team
+	leagues     map[int]*models.League
+	leagueTeams map[int][]int                     // league ID -> team IDs
+	stats       map[int]map[int]*models.TeamStats // league ID -> team ID -> stats
+	matches     map[int]*memoryMatch              // match ID -> match
+	ratings     map[int][]memoryRating            // league ID -> rating history
+
+	nextTeamID   int
+	nextLeagueID int
+	nextMatchID  int
+}
+
+// memoryMatch mirrors a row of the matches table
+type memoryMatch struct {
+	id         int
+	leagueID   int
+	week       int
+	homeTeamID int
+	awayTeamID int
+	homeScore  int
+	awayScore  int
+	cards      models.MatchCards
+	played     bool
+}
+
+// memoryRating mirrors a row of the team_ratings table
+type memoryRating struct {
+	teamID int
+	week   int
+	rating float64
+}
+
+var _ Repository = (*MemoryRepository)(nil)
+
+// NewMemoryRepository creates an in-memory store holding the sample teams
+func NewMemoryRepository() *MemoryRepository {
+	m := &MemoryRepository{}
+	m.reset()
+	return m
+}
+
+// reset removes all data and restores the sample teams; callers hold the lock
+func (m *MemoryRepository) reset() {
+	m.teams = make(map[int]*models.Team)
+	m.leagues = make(map[int]*models.League)
+	m.leagueTeams = make(map[int][]int)
+	m.stats = make(map[int]map[int]*models.TeamStats)
+	m.matches = make(map[int]*memoryMatch)
+	m.ratings = make(map[int][]memoryRating)
+
+	for _, team := range sampleTeams {
+		m.nextTeamID++
+		m.teams[m.nextTeamID] = &models.Team{ID: m.nextTeamID, Name: team.Name, Strength: team.Strength}
+	}
+}
+
+// GetAllTeams retrieves all teams ordered by name
+func (m *MemoryRepository) GetAllTeams(ctx context.Context) ([]models.Team, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var teams []models.Team
+	for _, team := range m.teams {
+		teams = append(teams, *team)
+	}
+	sortTeamsByName(teams)
+	return teams, nil
+}
+
+// AddTeam adds a new team
+func (m *MemoryRepository) AddTeam(ctx context.Context, name string, strength int) error {
+	_, err := m.AddTeamWithID(ctx, models.Team{Name: name, Strength: strength})
+	return err
+}
+
+// AddTeamWithID adds a new team and returns the created team with ID
+func (m *MemoryRepository) AddTeamWithID(ctx context.Context, team models.Team) (*models.Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkTeam(0, team); err != nil {
+		return nil, err
+	}
+
+	m.nextTeamID++
+	stored := &models.Team{ID: m.nextTeamID, Name: team.Name, Strength: team.Strength,
+		Attack: copyRating(team.Attack), Defence: copyRating(team.Defence)}
+	m.teams[stored.ID] = stored
+
+	created := *stored
+	return &created, nil
+}
+
+// checkTeam applies the constraints of the teams table; callers hold the lock
+func (m *MemoryRepository) checkTeam(id int, team models.Team) error {
+	if team.Name == "" || len(team.Name) > 100 {
+		return invalidf("team name must be 1-100 characters")
+	}
+	if team.Strength < 1 || team.Strength > 100 {
+		return invalidf("team strength must be between 1 and 100")
+	}
+	if team.Attack != nil && (*team.Attack < 1 || *team.Attack > 100) {
+		return invalidf("team attack must be between 1 and 100")
+	}
+	if team.Defence != nil && (*team.Defence < 1 || *team.Defence > 100) {
+		return invalidf("team defence must be between 1 and 100")
+	}
+	if existing := m.teamByName(team.Name); existing != nil && existing.ID != id {
+		return conflictf("team %q already exists", team.Name)
+	}
+	return nil
+}
+
+// copyRating copies an optional rating, so stored teams never share it with callers
+func copyRating(rating *int) *int {
+	if rating == nil {
+		return nil
+	}
+	value := *rating
+	return &value
+}
+
+// GetTeamByID retrieves a team by ID
+func (m *MemoryRepository) GetTeamByID(ctx context.Context, id int) (*models.Team, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	team, exists := m.teams[id]
+	if !exists {
+		return nil, notFoundf("team with ID %d not found", id)
+	}
+	found := *team
+	return &found, nil
+}
+
+// GetTeamByName retrieves a team by name
+func (m *MemoryRepository) GetTeamByName(ctx context.Context, name string) (*models.Team, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	team := m.teamByName(name)
+	if team == nil {
+		return nil, notFoundf("team %q not found", name)
+	}
+	found := *team
+	return &found, nil
+}
+
+// teamByName finds a team by name; callers hold the lock
+func (m *MemoryRepository) teamByName(name string) *models.Team {
+	for _, team := range m.teams {
+		if team.Name == name {
+			return team
+		}
+	}
+	return nil
+}
+
+// UpdateTeam updates an existing team
+func (m *MemoryRepository) UpdateTeam(ctx context.Context, team models.Team) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, exists := m.teams[team.ID]
+	if !exists {
+		return notFoundf("team with ID %d not found", team.ID)
+	}
+	if err := m.checkTeam(team.ID, team); err != nil {
+		return err
+	}
+
+	stored.Name = team.Name
+	stored.Strength = team.Strength
+	stored.Attack = copyRating(team.Attack)
+	stored.Defence = copyRating(team.Defence)
+	return nil
+}
+
+// DeleteTeam deletes a team together with its matches, stats and league entries
+func (m *MemoryRepository) DeleteTeam(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.teams[id]; !exists {
+		return notFoundf("team with ID %d not found", id)
+	}
+
+	for matchID, match := range m.matches {
+		if match.homeTeamID == id || match.awayTeamID == id {
+			delete(m.matches, matchID)
+		}
+	}
+	for _, leagueStats := range m.stats {
+		delete(leagueStats, id)
+	}
+	for leagueID, history := range m.ratings {
+		var kept []memoryRating
+		for _, rating := range history {
+			if rating.teamID != id {
+				kept = append(kept, rating)
+			}
+		}
+		m.ratings[leagueID] = kept
+	}
+	for leagueID, teamIDs := range m.leagueTeams {
+		var kept []int
+		for _, teamID := range teamIDs {
+			if teamID != id {
+				kept = append(kept, teamID)
+			}
+		}
+		m.leagueTeams[leagueID] = kept
+	}
+	delete(m.teams, id)
+
+	return nil
+}
+
+// TeamExists checks if a team exists by name
+func (m *MemoryRepository) TeamExists(ctx context.Context, name string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.teamByName(name) != nil, nil
+}
+
+// TeamExistsByID checks if a team exists by ID
+func (m *MemoryRepository) TeamExistsByID(ctx context.Context, id int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, exists := m.teams[id]
+	return exists, nil
+}
+
+// GetTeamCount returns the total number of teams
+func (m *MemoryRepository) GetTeamCount(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.teams), nil
+}
+
+// ClearTeams removes all teams, and with them every league
+func (m *MemoryRepository) ClearTeams(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.teams = make(map[int]*models.Team)
+	m.leagues = make(map[int]*models.League)
+	m.leagueTeams = make(map[int][]int)
+	m.stats = make(map[int]map[int]*models.TeamStats)
+	m.matches = make(map[int]*memoryMatch)
+	m.ratings = make(map[int][]memoryRating)
+	return nil
+}
+
+// CreateLeague creates a league with its teams, empty stats and fixtures.
+// Everything is checked before anything is stored, so a failure leaves no
+// partly created league behind.
+func (m *MemoryRepository) CreateLeague(ctx context.Context, league models.League, teamIDs []int, fixtures [][]models.Match) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if league.Name == "" || len(league.Name) > 100 {
+		return 0, invalidf("failed to create league: league name must be 1-100 characters")
+	}
+	if !services.ValidLegs(league.Legs) {
+		return 0, invalidf("failed to create league: legs must be 1, 2 or 4")
+	}
+	if err := services.ValidateRules(league.Rules); err != nil {
+		return 0, invalidf("failed to create league: %v", err)
+	}
+	if err := services.ValidateRatings(league.RatingSystem, league.SimulateWithRatings); err != nil {
+		return 0, invalidf("failed to create league: %v", err)
+	}
+
+	// The league teams must exist, each listed once, and be the only ones
+	// in the fixtures
+	inLeague := make(map[string]bool)
+	for _, teamID := range teamIDs {
+		team, exists := m.teams[teamID]
+		if !exists {
+			return 0, notFoundf("failed to add team to league: team with ID %d not found", teamID)
+		}
+		if inLeague[team.Name] {
+			return 0, conflictf("failed to add team to league: team %d is listed more than once", teamID)
+		}
+		inLeague[team.Name] = true
+	}
+	for _, weekMatches := range fixtures {
+		for _, match := range weekMatches {
+			if !inLeague[match.HomeTeam] || !inLeague[match.AwayTeam] {
+				return 0, invalidf("failed to store fixture: %s v %s is not between league teams", match.HomeTeam, match.AwayTeam)
+			}
+		}
+	}
+
+	rules := league.Rules
+	rules.TieBreakers = append([]string(nil), league.Rules.TieBreakers...)
+
+	m.nextLeagueID++
+	m.leagues[m.nextLeagueID] = &models.League{
+		ID:         m.nextLeagueID,
+		Name:       league.Name,
+		TotalWeeks: league.TotalWeeks,
+		Status:     "active",
+		Seed:       league.Seed,
+		Engine:     league.Engine,
+		Legs:       league.Legs,
+		Rules:      rules,
+
+		RatingSystem:        league.RatingSystem,
+		SimulateWithRatings: league.SimulateWithRatings,
+	}
+	leagueID := m.nextLeagueID
+
+	leagueStats := make(map[int]*models.TeamStats, len(teamIDs))
+	for _, teamID := range teamIDs {
+		leagueStats[teamID] = &models.TeamStats{}
+	}
+	m.stats[leagueID] = leagueStats
+	m.leagueTeams[leagueID] = append([]int(nil), teamIDs...)
+
+	// The fixtures were checked above, so storing them cannot fail
+	if err := m.storeFixtures(leagueID, fixtures); err != nil {
+		return 0, err
+	}
+
+	// Ratings start from the strengths the teams have today
+	if league.RatingSystem == services.RatingsElo {
+		m.storeRatingHistory(leagueID, 0, 0)
+	}
+	return leagueID, nil
+}
+
+// GetLeagues retrieves all leagues ordered by creation
+func (m *MemoryRepository) GetLeagues(ctx context.Context) ([]models.League, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	leagues := []models.League{}
+	for _, league := range m.leagues {
+		leagues = append(leagues, *league)
+	}
+	sort.Slice(leagues, func(i, j int) bool {
+		return leagues[i].ID < leagues[j].ID
+	})
+	return leagues, nil
+}
+
+// LeagueExists checks if a league exists by ID
+func (m *MemoryRepository) LeagueExists(ctx context.Context, leagueID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, exists := m.leagues[leagueID]
+	return exists, nil
+}
+
+// GetLatestLeagueID returns the ID of the most recently created league, or 0 if there is none
+func (m *MemoryRepository) GetLatestLeagueID(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	latest := 0
+	for id := range m.leagues {
+		if id > latest {
+			latest = id
+		}
+	}
+	return latest, nil
+}
+
+// GetLeagueTeams retrieves the teams taking part in a league
+func (m *MemoryRepository) GetLeagueTeams(ctx context.Context, leagueID int) ([]models.Team, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.leagueTeamList(leagueID), nil
+}
+
+// leagueTeamList returns the teams of a league ordered by name; callers hold the lock
+func (m *MemoryRepository) leagueTeamList(leagueID int) []models.Team {
+	var teams []models.Team
+	for _, teamID := range m.leagueTeams[leagueID] {
+		teams = append(teams, *m.teams[teamID])
+	}
+	sortTeamsByName(teams)
+	return teams
+}
+
+// GetLeagueStatus retrieves the current league status
+func (m *MemoryRepository) GetLeagueStatus(ctx context.Context, leagueID int) (models.LeagueResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var response models.LeagueResponse
+	league, exists := m.leagues[leagueID]
+	if !exists {
+		return response, notFoundf("league with ID %d not found", leagueID)
+	}
+
+	response.Name = league.Name
+	response.CurrentWeek = league.CurrentWeek
+	response.TotalWeeks = league.TotalWeeks
+	response.Status = league.Status
+	response.Seed = league.Seed
+	response.Engine = league.Engine
+	response.Legs = league.Legs
+	rules := league.Rules
+	response.Rules = &rules
+	response.RatingSystem = league.RatingSystem
+	response.SimulateWithRatings = league.SimulateWithRatings
+
+	// Calculate progress percentage
+	if response.TotalWeeks > 0 {
+		progress := float64(response.CurrentWeek) / float64(response.TotalWeeks) * 100
+		response.Progress = fmt.Sprintf("%.1f%%", progress)
+	}
+
+	return response, nil
+}
+
+// ClearLeague clears all data for a league
+func (m *MemoryRepository) ClearLeague(ctx context.Context, leagueID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for matchID, match := range m.matches {
+		if match.leagueID == leagueID {
+			delete(m.matches, matchID)
+		}
+	}
+	delete(m.stats, leagueID)
+	delete(m.ratings, leagueID)
+	delete(m.leagueTeams, leagueID)
+	delete(m.leagues, leagueID)
+	return nil
+}
+
+// UpdateTeamStats stores the statistics of a team in a league
+func (m *MemoryRepository) UpdateTeamStats(ctx context.Context, leagueID int, teamName string, stats models.TeamStats) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.setTeamStats(leagueID, teamName, stats)
+}
+
+// setTeamStats does the work of UpdateTeamStats; callers hold the lock
+func (m *MemoryRepository) setTeamStats(leagueID int, teamName string, stats models.TeamStats) error {
+	team := m.teamByName(teamName)
+	if team == nil {
+		return notFoundf("failed to update team stats: team %q not found", teamName)
+	}
+	leagueStats, exists := m.stats[leagueID]
+	if !exists {
+		return notFoundf("failed to update team stats: league with ID %d not found", leagueID)
+	}
+
+	stored := stats
+	stored.TeamName = ""
+	stored.Position = 0
+	leagueStats[team.ID] = &stored
+	return nil
+}
+
+// GetLeagueTable retrieves the current league table
+func (m *MemoryRepository) GetLeagueTable(ctx context.Context, leagueID int) ([]models.TeamStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.leagueTable(leagueID), nil
+}
+
+// leagueTable builds the standings from the stored stats and ranks them by
+// the league rules; callers hold the lock
+func (m *MemoryRepository) leagueTable(leagueID int) []models.TeamStats {
+	league, exists := m.leagues[leagueID]
+	if !exists {
+		return nil
+	}
+
+	var table []models.TeamStats
+	for teamID, stored := range m.stats[leagueID] {
+		stats := *stored
+		stats.TeamName = m.teams[teamID].Name
+		table = append(table, stats)
+	}
+
+	played := m.matchList(leagueID, func(match *memoryMatch) bool {
+		return match.played
+	})
+	return rankTable(table, played, league.Rules, league.Seed)
+}
+
+// RecalculateTeamStats rebuilds the stats of a league from its played matches
+func (m *MemoryRepository) RecalculateTeamStats(ctx context.Context, leagueID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.recalculateTeamStats(leagueID, 1)
+}
+
+// recalculateTeamStats does the work of RecalculateTeamStats. fromWeek is the
+// first week whose results changed; the ratings of the weeks before it are
+// kept. Callers hold the lock.
+func (m *MemoryRepository) recalculateTeamStats(leagueID, fromWeek int) error {
+	teams := m.leagueTeamList(leagueID)
+	played := m.matchList(leagueID, func(match *memoryMatch) bool {
+		return match.played
+	})
+
+	// Replay every stored result into a fresh table
+	league := services.NewGenerateLeague(teams)
+	stored, exists := m.leagues[leagueID]
+	if exists {
+		league.Rules = stored.Rules
+	}
+	league.ApplyResults(played)
+
+	for _, team := range teams {
+		if err := m.setTeamStats(leagueID, team.Name, toModelStats(league.TeamStats[team.Name])); err != nil {
+			return err
+		}
+	}
+
+	// A changed result moves the ratings of its week and every played week
+	// after it
+	if exists && stored.RatingSystem == services.RatingsElo {
+		from := min(fromWeek-1, stored.CurrentWeek)
+		m.storeRatingHistory(leagueID, from, stored.CurrentWeek)
+	}
+	return nil
+}
+
+// storeRatingHistory works out the ratings after each week from fromWeek to
+// toWeek, starting from the stored ones after fromWeek, and replaces those
+// weeks and any later ones. Earlier weeks are kept as they were stored.
+// Callers hold the lock.
+func (m *MemoryRepository) storeRatingHistory(leagueID, fromWeek, toWeek int) {
+	start := services.RatingsAfter(m.ratingHistory(leagueID), fromWeek)
+	played := m.matchList(leagueID, func(match *memoryMatch) bool {
+		return match.played
+	})
+
+	var history []memoryRating
+	for _, stored := range m.ratings[leagueID] {
+		if stored.week < fromWeek {
+			history = append(history, stored)
+		}
+	}
+	for _, rating := range services.RatingHistory(m.leagueTeamList(leagueID), start, fromWeek, toWeek, played) {
+		if team := m.teamByName(rating.TeamName); team != nil {
+			history = append(history, memoryRating{teamID: team.ID, week: rating.Week, rating: rating.Rating})
+		}
+	}
+	m.ratings[leagueID] = history
+}
+
+// GetRatingHistory retrieves the rating of every team of a league after each week
+func (m *MemoryRepository) GetRatingHistory(ctx context.Context, leagueID int) ([]models.TeamRating, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.ratingHistory(leagueID), nil
+}
+
+// ratingHistory does the work of GetRatingHistory; callers hold the lock
+func (m *MemoryRepository) ratingHistory(leagueID int) []models.TeamRating {
+	history := []models.TeamRating{}
+	for _, stored := range m.ratings[leagueID] {
+		history = append(history, models.TeamRating{
+			TeamName: m.teams[stored.teamID].Name,
+			Week:     stored.week,
+			Rating:   stored.rating,
+		})
+	}
+	sort.Slice(history, func(i, j int) bool {
+		if history[i].Week != history[j].Week {
+			return history[i].Week < history[j].Week
+		}
+		return history[i].TeamName < history[j].TeamName
+	})
+	return history
+}
+
+// storeFixtures stores the generated fixtures of a league; callers hold the lock
+func (m *MemoryRepository) storeFixtures(leagueID int, fixtures [][]models.Match) error {
+	// Create team name to ID mapping
+	teamMap := make(map[string]int)
+	for _, team := range m.leagueTeamList(leagueID) {
+		teamMap[team.Name] = team.ID
+	}
+
+	// Check every fixture before storing any of them
+	for _, weekMatches := range fixtures {
+		for _, match := range weekMatches {
+			if _, exists := teamMap[match.HomeTeam]; !exists {
+				return invalidf("failed to store fixture: team %q is not in league %d", match.HomeTeam, leagueID)
+			}
+			if _, exists := teamMap[match.AwayTeam]; !exists {
+				return invalidf("failed to store fixture: team %q is not in league %d", match.AwayTeam, leagueID)
+			}
+		}
+	}
+
+	for weekIndex, weekMatches := range fixtures {
+		for _, match := range weekMatches {
+			m.nextMatchID++
+			m.matches[m.nextMatchID] = &memoryMatch{
+				id:         m.nextMatchID,
+				leagueID:   leagueID,
+				week:       weekIndex + 1, // Convert to 1-based week numbers
+				homeTeamID: teamMap[match.HomeTeam],
+				awayTeamID: teamMap[match.AwayTeam],
+			}
+		}
+	}
+	return nil
+}
+
+// GetMatchSchedule gets the stored fixtures of a league grouped by week,
+// each with its match ID, status and score once played
+func (m *MemoryRepository) GetMatchSchedule(ctx context.Context, leagueID int) (map[int][]models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.schedule(leagueID), nil
+}
+
+// schedule does the work of GetMatchSchedule; callers hold the lock
+func (m *MemoryRepository) schedule(leagueID int) map[int][]models.Match {
+	schedule := make(map[int][]models.Match)
+	for _, match := range m.matchList(leagueID, nil) {
+		schedule[match.Week] = append(schedule[match.Week], match)
+	}
+	return schedule
+}
+
+// SaveMatch records a match result on its scheduled fixture
+func (m *MemoryRepository) SaveMatch(ctx context.Context, leagueID int, match models.Match) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.saveMatch(leagueID, match)
+}
+
+// saveMatch does the work of SaveMatch; callers hold the lock
+func (m *MemoryRepository) saveMatch(leagueID int, match models.Match) error {
+	stored, err := m.scheduledMatch(leagueID, match.ID)
+	if err != nil {
+		return err
+	}
+
+	stored.play(match.HomeScore, match.AwayScore, match.Cards)
+	return nil
+}
+
+// scheduledMatch returns the fixture of a league with the given ID, which
+// must not have been played yet; callers hold the lock
+func (m *MemoryRepository) scheduledMatch(leagueID, matchID int) (*memoryMatch, error) {
+	stored, exists := m.matches[matchID]
+	if !exists || stored.leagueID != leagueID || stored.played {
+		return nil, conflictf("match with ID %d is not a scheduled fixture", matchID)
+	}
+	return stored, nil
+}
+
+// GetMatches retrieves all played matches of a league
+func (m *MemoryRepository) GetMatches(ctx context.Context, leagueID int) ([]models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.matchList(leagueID, func(match *memoryMatch) bool {
+		return match.played
+	}), nil
+}
+
+// GetMatchesByWeek retrieves the played matches of a week
+func (m *MemoryRepository) GetMatchesByWeek(ctx context.Context, leagueID int, weekNumber int) ([]models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.matchList(leagueID, func(match *memoryMatch) bool {
+		return match.played && match.week == weekNumber
+	}), nil
+}
+
+// matchList returns the matches of a league selected by keep, ordered by
+// week and ID; a nil keep selects every match. Callers hold the lock.
+func (m *MemoryRepository) matchList(leagueID int, keep func(match *memoryMatch) bool) []models.Match {
+	var selected []*memoryMatch
+	for _, match := range m.matches {
+		if match.leagueID == leagueID && (keep == nil || keep(match)) {
+			selected = append(selected, match)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].week != selected[j].week {
+			return selected[i].week < selected[j].week
+		}
+		return selected[i].id < selected[j].id
+	})
+
+	var matches []models.Match
+	for _, match := range selected {
+		matches = append(matches, m.toModelMatch(match))
+	}
+	return matches
+}
+
+// toModelMatch resolves team names the way the SQL joins do; callers hold the lock
+func (m *MemoryRepository) toModelMatch(match *memoryMatch) models.Match {
+	return models.Match{
+		ID:        match.id,
+		Week:      match.week,
+		HomeTeam:  m.teams[match.homeTeamID].Name,
+		AwayTeam:  m.teams[match.awayTeamID].Name,
+		HomeScore: match.homeScore,
+		AwayScore: match.awayScore,
+		Cards:     match.cards,
+		Status:    matchStatus(match.played),
+	}
+}
+
+// GetMatch retrieves a single match of a league
+func (m *MemoryRepository) GetMatch(ctx context.Context, leagueID, matchID int) (*models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, exists := m.matches[matchID]
+	if !exists || stored.leagueID != leagueID {
+		return nil, notFoundf("match with ID %d not found", matchID)
+	}
+	match := m.toModelMatch(stored)
+	return &match, nil
+}
+
+// MatchExists checks if a match belongs to a league
+func (m *MemoryRepository) MatchExists(ctx context.Context, leagueID, matchID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, exists := m.matches[matchID]
+	return exists && stored.leagueID == leagueID, nil
+}
+
+// SetMatchResult records or corrects the score of a match and recalculates the table
+func (m *MemoryRepository) SetMatchResult(ctx context.Context, leagueID, matchID, homeScore, awayScore int, cards models.MatchCards) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.leagues[leagueID]; !exists {
+		return notFoundf("league with ID %d not found", leagueID)
+	}
+	stored, exists := m.matches[matchID]
+	if !exists || stored.leagueID != leagueID {
+		return notFoundf("match with ID %d not found", matchID)
+	}
+
+	stored.play(homeScore, awayScore, cards)
+	return m.recalculateTeamStats(leagueID, stored.week)
+}
+
+// UnplayMatch clears the score of a match and recalculates the table and the
+// ratings from its week on. A match of a played week is played again with
+// the next week.
+func (m *MemoryRepository) UnplayMatch(ctx context.Context, leagueID, matchID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.leagues[leagueID]; !exists {
+		return notFoundf("league with ID %d not found", leagueID)
+	}
+	stored, exists := m.matches[matchID]
+	if !exists || stored.leagueID != leagueID {
+		return notFoundf("match with ID %d not found", matchID)
+	}
+
+	stored.unplay()
+	return m.recalculateTeamStats(leagueID, stored.week)
+}
+
+// play records the score and the cards of a match
+func (match *memoryMatch) play(homeScore, awayScore int, cards models.MatchCards) {
+	match.homeScore = homeScore
+	match.awayScore = awayScore
+	match.cards = cards
+	match.played = true
+}
+
+// unplay turns a match back into a scheduled fixture
+func (match *memoryMatch) unplay() {
+	match.homeScore = 0
+	match.awayScore = 0
+	match.cards = models.MatchCards{}
+	match.played = false
+}
+
+// RewindLeague un-plays every match after the given week, restores the team
+// stats and moves the league back to that week. Fixtures are kept.
+func (m *MemoryRepository) RewindLeague(ctx context.Context, leagueID, week int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	league, exists := m.leagues[leagueID]
+	if !exists {
+		return notFoundf("league with ID %d not found", leagueID)
+	}
+
+	for _, match := range m.matches {
+		if match.leagueID == leagueID && match.week > week {
+			match.unplay()
+		}
+	}
+	league.CurrentWeek = week
+	league.Status = "active"
+
+	return m.recalculateTeamStats(leagueID, week+1)
+}
+
+// LoadSimulation restores the simulation of a league as PlayWeek would and
+// returns it with the fixtures still to be played, all read under one lock
+func (m *MemoryRepository) LoadSimulation(ctx context.Context, leagueID int) (*services.GenerateLeague, [][]models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	league, exists := m.leagues[leagueID]
+	if !exists {
+		return nil, nil, notFoundf("league with ID %d not found", leagueID)
+	}
+
+	sim, err := m.restoreSimulation(league)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sim, remainingFixtures(sim), nil
+}
+
+// restoreSimulation rebuilds the simulation of a stored league; callers hold
+// the lock
+func (m *MemoryRepository) restoreSimulation(league *models.League) (*services.GenerateLeague, error) {
+	played := m.matchList(league.ID, func(match *memoryMatch) bool {
+		return match.played
+	})
+	return restoreLeague(m.leagueTeamList(league.ID), *league, m.schedule(league.ID), played,
+		m.leagueTable(league.ID), m.ratingHistory(league.ID))
+}
+
+// PlayWeek plays the next week of a league. The store is locked for the
+// whole week, and the results are simulated and checked against their
+// fixtures before anything is written, so the week is all-or-nothing.
+func (m *MemoryRepository) PlayWeek(ctx context.Context, leagueID int) ([]models.Match, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	league, exists := m.leagues[leagueID]
+	if !exists {
+		return nil, notFoundf("league with ID %d not found", leagueID)
+	}
+
+	sim, err := m.restoreSimulation(league)
+	if err != nil {
+		return nil, err
+	}
+	teams := sim.Teams
+
+	nextWeek, err := nextWeekToPlay(sim, league.TotalWeeks)
+	if err != nil {
+		return nil, err
+	}
+	weekMatches, err := simulateWeek(sim, teams, nextWeek)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check every result against its fixture before changing anything
+	fixtures := make([]*memoryMatch, len(weekMatches))
+	for i, match := range weekMatches {
+		if fixtures[i], err = m.scheduledMatch(leagueID, match.ID); err != nil {
+			return nil, fmt.Errorf("failed to save match: %w", err)
+		}
+	}
+
+	// Nothing below can fail, so the week is stored in one step
+	for i, match := range weekMatches {
+		fixtures[i].play(match.HomeScore, match.AwayScore, match.Cards)
+	}
+	leagueStats := make(map[int]*models.TeamStats, len(teams))
+	for _, team := range teams {
+		stats := toModelStats(sim.TeamStats[team.Name])
+		stats.TeamName = ""
+		leagueStats[team.ID] = &stats
+	}
+	m.stats[leagueID] = leagueStats
+	if league.RatingSystem == services.RatingsElo {
+		m.storeRatingHistory(leagueID, firstRatedWeek(weekMatches, nextWeek)-1, nextWeek)
+	}
+	league.CurrentWeek = nextWeek
+
+	return weekMatches, nil
+}
+
+// PlayAllWeeks plays all remaining weeks in the league one week at a time
+func (m *MemoryRepository) PlayAllWeeks(ctx context.Context, leagueID int) ([]models.Match, error) {
+	return playAllWeeks(ctx, m, leagueID)
+}
+
+// ResetDatabase removes all data and restores the sample teams
+func (m *MemoryRepository) ResetDatabase(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.reset()
+	return nil
+}
+
+// sortTeamsByName orders teams the way the SQL queries do
+func sortTeamsByName(teams []models.Team) {
+	sort.Slice(teams, func(i, j int) bool {
+		return teams[i].Name < teams[j].Name
+	})
+}
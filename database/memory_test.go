@@ -0,0 +1,523 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"insider-league/Models"
+	"insider-league/Services"
+	"testing"
+)
+
+// newTestLeague creates a seeded double round-robin league of the sample
+// teams in a fresh memory repository
+func newTestLeague(t *testing.T) (*MemoryRepository, int) {
+	t.Helper()
+	return newRatedTestLeague(t, services.RatingsNone)
+}
+
+// newRatedTestLeague is newTestLeague with the given rating system
+func newRatedTestLeague(t *testing.T, ratingSystem string) (*MemoryRepository, int) {
+	t.Helper()
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	teams, err := repo.GetAllTeams(ctx)
+	if err != nil {
+		t.Fatalf("GetAllTeams: %v", err)
+	}
+	var teamIDs []int
+	for _, team := range teams {
+		teamIDs = append(teamIDs, team.ID)
+	}
+	fixtures := services.GenerateFixtureWithLegs(teams, services.DoubleRoundRobin)
+
+	leagueID, err := repo.CreateLeague(ctx, models.League{
+		Name:         "Test League",
+		TotalWeeks:   len(fixtures),
+		Seed:         42,
+		Engine:       services.DefaultEngineName,
+		Legs:         services.DoubleRoundRobin,
+		Rules:        services.DefaultRules(),
+		RatingSystem: ratingSystem,
+	}, teamIDs, fixtures)
+	if err != nil {
+		t.Fatalf("CreateLeague: %v", err)
+	}
+	return repo, leagueID
+}
+
+// weekMatch returns the first scheduled match of a week
+func weekMatch(t *testing.T, repo *MemoryRepository, leagueID, week int) models.Match {
+	t.Helper()
+	schedule, err := repo.GetMatchSchedule(context.Background(), leagueID)
+	if err != nil {
+		t.Fatalf("GetMatchSchedule: %v", err)
+	}
+	if len(schedule[week]) == 0 {
+		t.Fatalf("week %d has no matches", week)
+	}
+	return schedule[week][0]
+}
+
+func TestMemoryUnplayMatch(t *testing.T) {
+	ctx := context.Background()
+	repo, leagueID := newTestLeague(t)
+
+	for week := 1; week <= 2; week++ {
+		if _, err := repo.PlayWeek(ctx, leagueID); err != nil {
+			t.Fatalf("PlayWeek %d: %v", week, err)
+		}
+	}
+
+	// A result of a played week can be removed and the table follows
+	past := weekMatch(t, repo, leagueID, 1)
+	if err := repo.UnplayMatch(ctx, leagueID, past.ID); err != nil {
+		t.Fatalf("UnplayMatch(week 1): %v", err)
+	}
+	if match, _ := repo.GetMatch(ctx, leagueID, past.ID); match.Status != models.MatchScheduled {
+		t.Errorf("week 1 match status = %q, want scheduled", match.Status)
+	}
+	checkTable(t, repo, leagueID)
+
+	// Predictions count it as still to play, with the next week
+	_, remaining, err := repo.LoadSimulation(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("LoadSimulation: %v", err)
+	}
+	if len(remaining) != 4 || len(remaining[0]) != 3 || remaining[0][0].ID != past.ID {
+		t.Errorf("remaining fixtures start with %v, want the week 1 match and week 3", remaining[0])
+	}
+
+	// The next week plays it again, in its own week
+	played, err := repo.PlayWeek(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("PlayWeek 3: %v", err)
+	}
+	if len(played) != 3 || played[0].ID != past.ID || played[0].Week != 1 {
+		t.Fatalf("week 3 played %v, want the week 1 match first", played)
+	}
+	status, err := repo.GetLeagueStatus(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("GetLeagueStatus: %v", err)
+	}
+	if status.CurrentWeek != 3 {
+		t.Errorf("current week = %d, want 3", status.CurrentWeek)
+	}
+	checkTable(t, repo, leagueID)
+
+	// A result entered ahead of time can be removed as well
+	future := weekMatch(t, repo, leagueID, 5)
+	if err := repo.SetMatchResult(ctx, leagueID, future.ID, 2, 0, models.MatchCards{}); err != nil {
+		t.Fatalf("SetMatchResult: %v", err)
+	}
+	if err := repo.UnplayMatch(ctx, leagueID, future.ID); err != nil {
+		t.Fatalf("UnplayMatch(week 5): %v", err)
+	}
+	if match, _ := repo.GetMatch(ctx, leagueID, future.ID); match.Status != models.MatchScheduled {
+		t.Errorf("week 5 match status = %q, want scheduled", match.Status)
+	}
+}
+
+func TestMemoryUnplayMatchAfterTheSeason(t *testing.T) {
+	ctx := context.Background()
+	repo, leagueID := newTestLeague(t)
+
+	if _, err := repo.PlayAllWeeks(ctx, leagueID); err != nil {
+		t.Fatalf("PlayAllWeeks: %v", err)
+	}
+	last := weekMatch(t, repo, leagueID, 6)
+	if err := repo.UnplayMatch(ctx, leagueID, last.ID); err != nil {
+		t.Fatalf("UnplayMatch: %v", err)
+	}
+
+	// The last week is played again for the removed result only
+	played, err := repo.PlayWeek(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("PlayWeek: %v", err)
+	}
+	if len(played) != 1 || played[0].ID != last.ID {
+		t.Errorf("played %v, want only match %d", played, last.ID)
+	}
+	status, err := repo.GetLeagueStatus(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("GetLeagueStatus: %v", err)
+	}
+	if status.CurrentWeek != 6 {
+		t.Errorf("current week = %d, want 6", status.CurrentWeek)
+	}
+	checkTable(t, repo, leagueID)
+
+	if _, err := repo.PlayWeek(ctx, leagueID); !errors.Is(err, ErrSeasonComplete) {
+		t.Errorf("PlayWeek of a finished season error = %v, want ErrSeasonComplete", err)
+	}
+}
+
+// tableFromMatches adds up the played matches with 3 points for a win and 1
+// for a draw, the way the table should come out
+func tableFromMatches(matches []models.Match) map[string]models.TeamStats {
+	table := make(map[string]models.TeamStats)
+	record := func(team string, goalsFor, goalsAgainst int) {
+		stats := table[team]
+		stats.TeamName = team
+		stats.Played++
+		stats.GoalsFor += goalsFor
+		stats.GoalsAgainst += goalsAgainst
+		switch {
+		case goalsFor > goalsAgainst:
+			stats.Won++
+			stats.Points += 3
+		case goalsFor == goalsAgainst:
+			stats.Drawn++
+			stats.Points++
+		default:
+			stats.Lost++
+		}
+		table[team] = stats
+	}
+	for _, match := range matches {
+		if match.Status != models.MatchPlayed {
+			continue
+		}
+		record(match.HomeTeam, match.HomeScore, match.AwayScore)
+		record(match.AwayTeam, match.AwayScore, match.HomeScore)
+	}
+	return table
+}
+
+// checkTable compares the stored table with one added up from the stored matches
+func checkTable(t *testing.T, repo *MemoryRepository, leagueID int) []models.TeamStats {
+	t.Helper()
+	ctx := context.Background()
+
+	matches, err := repo.GetMatches(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("GetMatches: %v", err)
+	}
+	table, err := repo.GetLeagueTable(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("GetLeagueTable: %v", err)
+	}
+
+	want := tableFromMatches(matches)
+	for i, got := range table {
+		expected := want[got.TeamName]
+		if got.Played != expected.Played || got.Won != expected.Won || got.Drawn != expected.Drawn ||
+			got.Lost != expected.Lost || got.GoalsFor != expected.GoalsFor ||
+			got.GoalsAgainst != expected.GoalsAgainst || got.Points != expected.Points {
+			t.Errorf("%s: got %+v, want %+v", got.TeamName, got, expected)
+		}
+		if i > 0 && table[i-1].Points < got.Points {
+			t.Errorf("%s with %d points is below %s with %d", got.TeamName, got.Points, table[i-1].TeamName, table[i-1].Points)
+		}
+	}
+	return table
+}
+
+func TestMemoryLeagueFlow(t *testing.T) {
+	ctx := context.Background()
+	repo, leagueID := newTestLeague(t)
+
+	// Play three weeks
+	var firstRun [][]models.Match
+	for week := 1; week <= 3; week++ {
+		matches, err := repo.PlayWeek(ctx, leagueID)
+		if err != nil {
+			t.Fatalf("PlayWeek %d: %v", week, err)
+		}
+		if len(matches) != 2 {
+			t.Fatalf("week %d played %d matches, want 2", week, len(matches))
+		}
+		firstRun = append(firstRun, matches)
+	}
+	status, err := repo.GetLeagueStatus(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("GetLeagueStatus: %v", err)
+	}
+	if status.CurrentWeek != 3 {
+		t.Errorf("current week = %d, want 3", status.CurrentWeek)
+	}
+	checkTable(t, repo, leagueID)
+
+	// Correct a result of week 2
+	edited := firstRun[1][0]
+	cards := models.MatchCards{HomeYellow: 2, AwayYellow: 1, AwayRed: 1}
+	if err := repo.SetMatchResult(ctx, leagueID, edited.ID, 5, 0, cards); err != nil {
+		t.Fatalf("SetMatchResult: %v", err)
+	}
+	match, err := repo.GetMatch(ctx, leagueID, edited.ID)
+	if err != nil {
+		t.Fatalf("GetMatch: %v", err)
+	}
+	if match.HomeScore != 5 || match.AwayScore != 0 {
+		t.Errorf("edited match is %d-%d, want 5-0", match.HomeScore, match.AwayScore)
+	}
+	if match.Cards != cards {
+		t.Errorf("edited match has cards %+v, want %+v", match.Cards, cards)
+	}
+	checkTable(t, repo, leagueID)
+
+	// Rewind to the end of week 1: later weeks are un-played, the edit with them
+	if err := repo.RewindLeague(ctx, leagueID, 1); err != nil {
+		t.Fatalf("RewindLeague: %v", err)
+	}
+	table := checkTable(t, repo, leagueID)
+	for _, stats := range table {
+		if stats.Played != 1 {
+			t.Errorf("%s played %d matches after the rewind, want 1", stats.TeamName, stats.Played)
+		}
+	}
+	matches, err := repo.GetMatches(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("GetMatches: %v", err)
+	}
+	for _, match := range matches {
+		if match.Status == models.MatchPlayed && match.Week > 1 {
+			t.Errorf("match %d of week %d is still played", match.ID, match.Week)
+		}
+		if match.Week > 1 && match.Cards != (models.MatchCards{}) {
+			t.Errorf("match %d of week %d still has cards %+v", match.ID, match.Week, match.Cards)
+		}
+	}
+
+	// Replaying a week gives the same results, since they only depend on
+	// the seed, the week and the matches before it
+	replayed, err := repo.PlayWeek(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("PlayWeek after rewind: %v", err)
+	}
+	for i, match := range replayed {
+		original := firstRun[1][i]
+		if match.ID != original.ID || match.HomeScore != original.HomeScore || match.AwayScore != original.AwayScore {
+			t.Errorf("replayed %+v, first played %+v", match, original)
+		}
+	}
+	checkTable(t, repo, leagueID)
+}
+
+func TestMemoryPlayWeekFailureChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	repo, leagueID := newTestLeague(t)
+
+	if _, err := repo.PlayWeek(ctx, leagueID); err != nil {
+		t.Fatalf("PlayWeek: %v", err)
+	}
+	before := checkTable(t, repo, leagueID)
+
+	// An engine that no longer exists fails the week
+	repo.leagues[leagueID].Engine = "missing"
+	if _, err := repo.PlayWeek(ctx, leagueID); err == nil {
+		t.Fatal("PlayWeek with an unknown engine succeeded")
+	}
+
+	status, err := repo.GetLeagueStatus(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("GetLeagueStatus: %v", err)
+	}
+	if status.CurrentWeek != 1 {
+		t.Errorf("current week = %d, want 1", status.CurrentWeek)
+	}
+	after := checkTable(t, repo, leagueID)
+	for i := range before {
+		if before[i] != after[i] {
+			t.Errorf("table row %d changed from %+v to %+v", i, before[i], after[i])
+		}
+	}
+}
+
+func TestMemoryCreateLeagueIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	teams, err := repo.GetAllTeams(ctx)
+	if err != nil {
+		t.Fatalf("GetAllTeams: %v", err)
+	}
+	fixtures := services.GenerateFixtureWithLegs(teams, services.DoubleRoundRobin)
+
+	// A missing team fails the whole league, fixtures included
+	_, err = repo.CreateLeague(ctx, models.League{
+		Name:         "Broken",
+		TotalWeeks:   len(fixtures),
+		Engine:       services.DefaultEngineName,
+		Legs:         services.DoubleRoundRobin,
+		Rules:        services.DefaultRules(),
+		RatingSystem: services.RatingsNone,
+	}, []int{teams[0].ID, teams[1].ID, teams[2].ID, 99}, fixtures)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("CreateLeague error = %v, want ErrNotFound", err)
+	}
+
+	leagues, err := repo.GetLeagues(ctx)
+	if err != nil {
+		t.Fatalf("GetLeagues: %v", err)
+	}
+	if len(leagues) != 0 || len(repo.matches) != 0 {
+		t.Errorf("failed CreateLeague left %d leagues and %d matches", len(leagues), len(repo.matches))
+	}
+}
+
+// ratingsByWeek groups a league's rating history by week
+func ratingsByWeek(t *testing.T, repo *MemoryRepository, leagueID int) map[int]map[string]float64 {
+	t.Helper()
+	history, err := repo.GetRatingHistory(context.Background(), leagueID)
+	if err != nil {
+		t.Fatalf("GetRatingHistory: %v", err)
+	}
+	weeks := make(map[int]map[string]float64)
+	for _, rating := range history {
+		if weeks[rating.Week] == nil {
+			weeks[rating.Week] = make(map[string]float64)
+		}
+		weeks[rating.Week][rating.TeamName] = rating.Rating
+	}
+	return weeks
+}
+
+// checkWeeksKept fails when a week of before differs from the same week of after
+func checkWeeksKept(t *testing.T, before, after map[int]map[string]float64, weeks ...int) {
+	t.Helper()
+	for _, week := range weeks {
+		if len(after[week]) != len(before[week]) {
+			t.Errorf("week %d has %d ratings, had %d", week, len(after[week]), len(before[week]))
+		}
+		for team, rating := range before[week] {
+			if after[week][team] != rating {
+				t.Errorf("week %d: %s rating changed from %.2f to %.2f", week, team, rating, after[week][team])
+			}
+		}
+	}
+}
+
+func TestMemoryRatingHistory(t *testing.T) {
+	ctx := context.Background()
+	repo, leagueID := newRatedTestLeague(t, services.RatingsElo)
+
+	if weeks := ratingsByWeek(t, repo, leagueID); len(weeks) != 1 || len(weeks[0]) != 4 {
+		t.Fatalf("a new league has ratings for weeks %v, want week 0 only", weeks)
+	}
+	for week := 1; week <= 2; week++ {
+		if _, err := repo.PlayWeek(ctx, leagueID); err != nil {
+			t.Fatalf("PlayWeek %d: %v", week, err)
+		}
+	}
+	played := ratingsByWeek(t, repo, leagueID)
+	if len(played) != 3 {
+		t.Fatalf("got ratings for %d weeks, want 3", len(played))
+	}
+
+	// Editing a team's strength leaves the stored weeks alone
+	teams, err := repo.GetAllTeams(ctx)
+	if err != nil {
+		t.Fatalf("GetAllTeams: %v", err)
+	}
+	team := teams[0]
+	team.Strength = 1
+	if err := repo.UpdateTeam(ctx, team); err != nil {
+		t.Fatalf("UpdateTeam: %v", err)
+	}
+	if _, err := repo.PlayWeek(ctx, leagueID); err != nil {
+		t.Fatalf("PlayWeek 3: %v", err)
+	}
+	afterEdit := ratingsByWeek(t, repo, leagueID)
+	checkWeeksKept(t, played, afterEdit, 0, 1, 2)
+	if len(afterEdit[3]) != 4 {
+		t.Errorf("week 3 has %d ratings, want 4", len(afterEdit[3]))
+	}
+
+	// Correcting a week 2 result only moves week 2 onwards
+	match := weekMatch(t, repo, leagueID, 2)
+	if err := repo.SetMatchResult(ctx, leagueID, match.ID, match.HomeScore+5, match.AwayScore, models.MatchCards{}); err != nil {
+		t.Fatalf("SetMatchResult: %v", err)
+	}
+	corrected := ratingsByWeek(t, repo, leagueID)
+	checkWeeksKept(t, afterEdit, corrected, 0, 1)
+	if corrected[2][match.HomeTeam] <= afterEdit[2][match.HomeTeam] {
+		t.Errorf("%s rating after week 2 = %.2f, want above %.2f after a bigger win",
+			match.HomeTeam, corrected[2][match.HomeTeam], afterEdit[2][match.HomeTeam])
+	}
+
+	// Removing a week 1 result moves week 1 onwards, and playing it again
+	// puts the history back together
+	removed := weekMatch(t, repo, leagueID, 1)
+	if err := repo.UnplayMatch(ctx, leagueID, removed.ID); err != nil {
+		t.Fatalf("UnplayMatch: %v", err)
+	}
+	unplayed := ratingsByWeek(t, repo, leagueID)
+	checkWeeksKept(t, corrected, unplayed, 0)
+	if unplayed[1][removed.HomeTeam] != unplayed[0][removed.HomeTeam] {
+		t.Errorf("%s rating moved in week 1 without a match", removed.HomeTeam)
+	}
+	if _, err := repo.PlayWeek(ctx, leagueID); err != nil {
+		t.Fatalf("PlayWeek 4: %v", err)
+	}
+	if len(ratingsByWeek(t, repo, leagueID)) != 5 {
+		t.Errorf("want ratings for weeks 0 to 4")
+	}
+
+	// Rewinding drops the later weeks and keeps the rest
+	if err := repo.RewindLeague(ctx, leagueID, 1); err != nil {
+		t.Fatalf("RewindLeague: %v", err)
+	}
+	rewound := ratingsByWeek(t, repo, leagueID)
+	if len(rewound) != 2 {
+		t.Errorf("got ratings for %d weeks after the rewind, want 2", len(rewound))
+	}
+	checkWeeksKept(t, played, rewound, 0)
+}
+
+func TestMemoryLoadSimulation(t *testing.T) {
+	ctx := context.Background()
+	repo, leagueID := newTestLeague(t)
+
+	for week := 1; week <= 2; week++ {
+		if _, err := repo.PlayWeek(ctx, leagueID); err != nil {
+			t.Fatalf("PlayWeek %d: %v", week, err)
+		}
+	}
+	// A result entered ahead of time is not left to play
+	ahead := weekMatch(t, repo, leagueID, 4)
+	if err := repo.SetMatchResult(ctx, leagueID, ahead.ID, 1, 0, models.MatchCards{}); err != nil {
+		t.Fatalf("SetMatchResult: %v", err)
+	}
+
+	league, remaining, err := repo.LoadSimulation(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("LoadSimulation: %v", err)
+	}
+	if league.CurrentWeek != 2 {
+		t.Errorf("current week = %d, want 2", league.CurrentWeek)
+	}
+	matches := 0
+	for _, weekMatches := range remaining {
+		for _, match := range weekMatches {
+			if match.Week <= 2 || match.ID == ahead.ID {
+				t.Errorf("match %d of week %d is left to play", match.ID, match.Week)
+			}
+			matches++
+		}
+	}
+	if matches != 7 {
+		t.Errorf("%d matches left to play, want 7", matches)
+	}
+	// Nor does it count in form before its week
+	for _, team := range league.Teams {
+		if form := league.Form(team.Name); len(form.Results) != 2 {
+			t.Errorf("%s form is %q, want the 2 weeks played", team.Name, form.Results)
+		}
+	}
+
+	table, err := repo.GetLeagueTable(ctx, leagueID)
+	if err != nil {
+		t.Fatalf("GetLeagueTable: %v", err)
+	}
+	for _, stats := range table {
+		if got := league.TeamStats[stats.TeamName]; got.Points != stats.Points || got.Played != stats.Played {
+			t.Errorf("%s restored with %d points from %d matches, stored %d from %d",
+				stats.TeamName, got.Points, got.Played, stats.Points, stats.Played)
+		}
+	}
+
+	if _, _, err := repo.LoadSimulation(ctx, leagueID+1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("LoadSimulation of a missing league error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles holds the numbered schema migrations, named
+// NNNN_description.sql. Applied migrations must never be edited; schema
+// changes go into a new file with the next number.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey is the advisory lock that keeps two servers from
+// migrating the same database at once
+const migrationLockKey = 0x1ea9
+
+// migration is a single numbered schema change
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// loadMigrations reads the embedded migrations ordered by version
+func loadMigrations() ([]migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, found := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if !found || err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s must be named NNNN_description.sql", name)
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", name, err)
+		}
+		migrations = append(migrations, migration{Version: version, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrate brings the schema up to date by applying, in order, every embedded
+// migration not yet recorded in schema_migrations. Each migration runs in its
+// own transaction together with its schema_migrations row.
+func Migrate(db *sql.DB) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	for _, m := range migrations {
+		applied, err := applyMigration(db, m)
+		if err != nil {
+			return err
+		}
+		if applied {
+			fmt.Printf("Applied migration %s\n", m.Name)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs a migration unless it has already been applied
+func applyMigration(db *sql.DB, m migration) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	// Wait for any other server migrating the same database
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", migrationLockKey); err != nil {
+		return false, fmt.Errorf("failed to lock migrations: %v", err)
+	}
+
+	var applied bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)", m.Version).Scan(&applied)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %s: %v", m.Name, err)
+	}
+	if applied {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return false, fmt.Errorf("failed to apply migration %s: %v", m.Name, err)
+	}
+
+	_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration %s: %v", m.Name, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration %s: %v", m.Name, err)
+	}
+
+	return true, nil
+}
+
+// SchemaVersion returns the newest migration applied to db, 0 when none is
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+	return version, nil
+}
+
+// LatestSchemaVersion returns the newest migration embedded in the binary,
+// the version Migrate brings a database to
+func LatestSchemaVersion() (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
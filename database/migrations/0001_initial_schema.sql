@@ -1,9 +1,5 @@
--- Football League Database Schema
--- Run this script in PostgreSQL to create your database
-
--- Create database (run this first, separately)
--- CREATE DATABASE football_league;
--- \c football_league;
+-- Tables of the first release. IF NOT EXISTS lets databases created from the
+-- old schema.sql adopt the migrations without changes.
 
 -- Teams table
 CREATE TABLE IF NOT EXISTS teams (
@@ -12,7 +8,7 @@ CREATE TABLE IF NOT EXISTS teams (
     strength INTEGER NOT NULL CHECK (strength >= 1 AND strength <= 100)
 );
 
--- Leagues table  
+-- Leagues table
 CREATE TABLE IF NOT EXISTS leagues (
     id SERIAL PRIMARY KEY,
     name VARCHAR(100) NOT NULL,
@@ -58,12 +54,3 @@ CREATE TABLE IF NOT EXISTS team_stats (
     goal_difference INTEGER GENERATED ALWAYS AS (goals_for - goals_against) STORED,
     UNIQUE(league_id, team_id)
 );
-
--- Insert sample teams (4 teams for smaller league)
-INSERT INTO teams (name, strength) VALUES 
-    ('Arsenal', 70),
-    ('Chelsea', 85),
-    ('Liverpool', 75),
-    ('Manchester City', 92)
-ON CONFLICT (name) DO NOTHING;
-
@@ -0,0 +1,7 @@
+-- Per-league seed, match engine and number of legs. Leagues that already
+-- exist were played with the classic scoring, so they keep it; new leagues
+-- default to Poisson.
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS seed BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS engine VARCHAR(32) NOT NULL DEFAULT 'classic';
+ALTER TABLE leagues ALTER COLUMN engine SET DEFAULT 'poisson';
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS legs INTEGER NOT NULL DEFAULT 2 CHECK (legs IN (1, 2, 4));
@@ -0,0 +1,19 @@
+-- Results used to be stored as a second, played row next to their fixture.
+-- Copy each of those results onto the fixture and remove the extra rows, so
+-- every fixture keeps one row and one match ID.
+UPDATE matches f
+SET home_score = p.home_score, away_score = p.away_score, played = true
+FROM matches p
+WHERE p.league_id = f.league_id AND p.week_number = f.week_number
+  AND p.home_team_id = f.home_team_id AND p.away_team_id = f.away_team_id
+  AND p.id > f.id AND p.played = true AND f.played = false;
+
+DELETE FROM matches p
+USING matches f
+WHERE p.league_id = f.league_id AND p.week_number = f.week_number
+  AND p.home_team_id = f.home_team_id AND p.away_team_id = f.away_team_id
+  AND p.id > f.id;
+
+-- A pairing is scheduled at most once per week
+CREATE UNIQUE INDEX IF NOT EXISTS matches_fixture_key
+    ON matches (league_id, week_number, home_team_id, away_team_id);
@@ -0,0 +1,9 @@
+-- Per-league rules: points per result, an optional bonus for scoring at
+-- least bonus_goals goals in a match, and the ordered tie-breakers. Existing
+-- leagues keep the rules they were played under.
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_win INTEGER NOT NULL DEFAULT 3 CHECK (points_win >= 0);
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_draw INTEGER NOT NULL DEFAULT 1 CHECK (points_draw >= 0);
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_loss INTEGER NOT NULL DEFAULT 0 CHECK (points_loss >= 0);
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS bonus_points INTEGER NOT NULL DEFAULT 0 CHECK (bonus_points >= 0);
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS bonus_goals INTEGER NOT NULL DEFAULT 0 CHECK (bonus_goals >= 0);
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS tie_breakers TEXT[] NOT NULL DEFAULT '{goal_difference,goals_for}';
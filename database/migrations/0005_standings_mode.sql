@@ -0,0 +1,4 @@
+-- How teams level on points are ordered: straight to the tie-breakers, or
+-- first by a mini-league of the matches between them
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS standings_mode VARCHAR(16) NOT NULL DEFAULT 'overall'
+    CHECK (standings_mode IN ('overall', 'head_to_head'));
@@ -0,0 +1,13 @@
+-- Optional Elo ratings: the rating system of each league, whether matches
+-- are simulated with the ratings, and every team's rating after each week
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS rating_system VARCHAR(16) NOT NULL DEFAULT 'none'
+    CHECK (rating_system IN ('none', 'elo'));
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS simulate_with_ratings BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS team_ratings (
+    league_id INTEGER NOT NULL REFERENCES leagues(id) ON DELETE CASCADE,
+    team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    week_number INTEGER NOT NULL CHECK (week_number >= 0),
+    rating DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (league_id, team_id, week_number)
+);
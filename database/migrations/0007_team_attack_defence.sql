@@ -0,0 +1,3 @@
+-- Optional attack and defence ratings; a team without them uses its strength
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS attack INTEGER CHECK (attack >= 1 AND attack <= 100);
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS defence INTEGER CHECK (defence >= 1 AND defence <= 100);
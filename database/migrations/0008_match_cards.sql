@@ -0,0 +1,6 @@
+-- Yellow and red cards of both teams in a match, for the fair play
+-- tie-breaker. Matches played before cards were recorded have none.
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS home_yellow_cards INTEGER NOT NULL DEFAULT 0 CHECK (home_yellow_cards >= 0);
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS home_red_cards INTEGER NOT NULL DEFAULT 0 CHECK (home_red_cards >= 0);
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS away_yellow_cards INTEGER NOT NULL DEFAULT 0 CHECK (away_yellow_cards >= 0);
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS away_red_cards INTEGER NOT NULL DEFAULT 0 CHECK (away_red_cards >= 0);
@@ -1,17 +1,141 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"insider-league/Models"
 	"insider-league/Services"
+
+	"github.com/lib/pq"
 )
 
+// Repository is the storage used by the handlers. TeamRepository keeps the
+// data in PostgreSQL and MemoryRepository keeps it in process memory.
+type Repository interface {
+	// Teams
+	GetAllTeams(ctx context.Context) ([]models.Team, error)
+	AddTeam(ctx context.Context, name string, strength int) error
+	AddTeamWithID(ctx context.Context, team models.Team) (*models.Team, error)
+	GetTeamByID(ctx context.Context, id int) (*models.Team, error)
+	GetTeamByName(ctx context.Context, name string) (*models.Team, error)
+	UpdateTeam(ctx context.Context, team models.Team) error
+	DeleteTeam(ctx context.Context, id int) error
+	TeamExists(ctx context.Context, name string) (bool, error)
+	TeamExistsByID(ctx context.Context, id int) (bool, error)
+	GetTeamCount(ctx context.Context) (int, error)
+	ClearTeams(ctx context.Context) error
+
+	// Leagues
+	CreateLeague(ctx context.Context, league models.League, teamIDs []int, fixtures [][]models.Match) (int, error)
+	GetLeagues(ctx context.Context) ([]models.League, error)
+	LeagueExists(ctx context.Context, leagueID int) (bool, error)
+	GetLatestLeagueID(ctx context.Context) (int, error)
+	GetLeagueTeams(ctx context.Context, leagueID int) ([]models.Team, error)
+	GetLeagueStatus(ctx context.Context, leagueID int) (models.LeagueResponse, error)
+	ClearLeague(ctx context.Context, leagueID int) error
+
+	// Standings
+	UpdateTeamStats(ctx context.Context, leagueID int, teamName string, stats models.TeamStats) error
+	GetLeagueTable(ctx context.Context, leagueID int) ([]models.TeamStats, error)
+	RecalculateTeamStats(ctx context.Context, leagueID int) error
+	GetRatingHistory(ctx context.Context, leagueID int) ([]models.TeamRating, error)
+
+	// Fixtures and results
+	GetMatchSchedule(ctx context.Context, leagueID int) (map[int][]models.Match, error)
+	SaveMatch(ctx context.Context, leagueID int, match models.Match) error
+	GetMatches(ctx context.Context, leagueID int) ([]models.Match, error)
+	GetMatchesByWeek(ctx context.Context, leagueID int, weekNumber int) ([]models.Match, error)
+	GetMatch(ctx context.Context, leagueID, matchID int) (*models.Match, error)
+	MatchExists(ctx context.Context, leagueID, matchID int) (bool, error)
+	SetMatchResult(ctx context.Context, leagueID, matchID, homeScore, awayScore int, cards models.MatchCards) error
+	UnplayMatch(ctx context.Context, leagueID, matchID int) error
+
+	// Simulation
+	LoadSimulation(ctx context.Context, leagueID int) (*services.GenerateLeague, [][]models.Match, error)
+	PlayWeek(ctx context.Context, leagueID int) ([]models.Match, error)
+	PlayAllWeeks(ctx context.Context, leagueID int) ([]models.Match, error)
+	RewindLeague(ctx context.Context, leagueID, week int) error
+
+	// ResetDatabase removes all data and restores the sample teams
+	ResetDatabase(ctx context.Context) error
+}
+
+var _ Repository = (*TeamRepository)(nil)
+
 // TeamRepository handles team-related database operations
-type TeamRepository struct{}
+type TeamRepository struct {
+	tx *sql.Tx // set while running inside inLeagueTransaction
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// db returns the transaction the repository is bound to, or the connection pool
+func (r *TeamRepository) db() querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return DB
+}
+
+// inLeagueTransaction runs fn inside a single transaction that holds a row
+// lock on the league, so changes to one league are all-or-nothing and
+// serialized. fn must use the repository it is given.
+func (r *TeamRepository) inLeagueTransaction(ctx context.Context, leagueID int, fn func(txRepo *TeamRepository) error) error {
+	// Already inside a transaction, the lock is held
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	return r.inTransaction(ctx, func(txRepo *TeamRepository) error {
+		// Lock the league row until the transaction ends
+		var lockedID int
+		err := txRepo.tx.QueryRowContext(ctx, "SELECT id FROM leagues WHERE id = $1 FOR UPDATE", leagueID).Scan(&lockedID)
+		if err == sql.ErrNoRows {
+			return notFoundf("league with ID %d not found", leagueID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock league: %v", err)
+		}
+
+		return fn(txRepo)
+	})
+}
+
+// inTransaction runs fn inside a single transaction, committed only when fn
+// succeeds. fn must use the repository it is given.
+func (r *TeamRepository) inTransaction(ctx context.Context, fn func(txRepo *TeamRepository) error) error {
+	// Already inside a transaction
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	if err := fn(&TeamRepository{tx: tx}); err != nil {
+		return err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
 
 // GetAllTeams retrieves all teams from the database
-func (r *TeamRepository) GetAllTeams() ([]models.Team, error) {
-	rows, err := DB.Query("SELECT id, name, strength FROM teams ORDER BY name")
+func (r *TeamRepository) GetAllTeams(ctx context.Context) ([]models.Team, error) {
+	rows, err := r.db().QueryContext(ctx, "SELECT id, name, strength, attack, defence FROM teams ORDER BY name")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query teams: %v", err)
 	}
@@ -20,7 +144,7 @@ func (r *TeamRepository) GetAllTeams() ([]models.Team, error) {
 	var teams []models.Team
 	for rows.Next() {
 		var team models.Team
-		if err := rows.Scan(&team.ID, &team.Name, &team.Strength); err != nil {
+		if err := rows.Scan(&team.ID, &team.Name, &team.Strength, &team.Attack, &team.Defence); err != nil {
 			return nil, fmt.Errorf("failed to scan team: %v", err)
 		}
 		teams = append(teams, team)
@@ -29,72 +153,205 @@ func (r *TeamRepository) GetAllTeams() ([]models.Team, error) {
 	return teams, nil
 }
 
-// CreateLeague creates a new league in the database
-func (r *TeamRepository) CreateLeague(name string, totalWeeks int) (int, error) {
+// CreateLeague creates a league with its teams, empty stats and fixtures in
+// one transaction, so a failure leaves no partly created league behind
+func (r *TeamRepository) CreateLeague(ctx context.Context, league models.League, teamIDs []int, fixtures [][]models.Match) (int, error) {
+	var leagueID int
+	err := r.inTransaction(ctx, func(txRepo *TeamRepository) error {
+		var err error
+		if leagueID, err = txRepo.insertLeague(ctx, league); err != nil {
+			return err
+		}
+		if err := txRepo.addTeamsToLeague(ctx, leagueID, teamIDs); err != nil {
+			return err
+		}
+		if err := txRepo.initializeTeamStats(ctx, leagueID, teamIDs); err != nil {
+			return err
+		}
+		if err := txRepo.storeFixtures(ctx, leagueID, fixtures); err != nil {
+			return err
+		}
+
+		// Ratings start from the strengths the teams have today
+		if league.RatingSystem == services.RatingsElo {
+			return txRepo.storeRatingHistory(ctx, leagueID, 0, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return leagueID, nil
+}
+
+// insertLeague adds the leagues row of a new league
+func (r *TeamRepository) insertLeague(ctx context.Context, league models.League) (int, error) {
+	if err := services.ValidateRules(league.Rules); err != nil {
+		return 0, invalidf("failed to create league: %v", err)
+	}
+	if err := services.ValidateRatings(league.RatingSystem, league.SimulateWithRatings); err != nil {
+		return 0, invalidf("failed to create league: %v", err)
+	}
+
+	rules := league.Rules
 	var leagueID int
-	err := DB.QueryRow("INSERT INTO leagues (name, total_weeks) VALUES ($1, $2) RETURNING id", 
-		name, totalWeeks).Scan(&leagueID)
+	err := r.db().QueryRowContext(ctx, `
+		INSERT INTO leagues (name, total_weeks, seed, engine, legs, rating_system, simulate_with_ratings, `+rulesColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`,
+		league.Name, league.TotalWeeks, league.Seed, league.Engine, league.Legs,
+		league.RatingSystem, league.SimulateWithRatings,
+		rules.PointsWin, rules.PointsDraw, rules.PointsLoss, rules.BonusPoints, rules.BonusGoals,
+		pq.Array(rules.TieBreakers), rules.StandingsMode).Scan(&leagueID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create league: %v", err)
+		return 0, classify(err, "failed to create league")
 	}
 	return leagueID, nil
 }
 
-// AddTeamsToLeague adds teams to a league
-func (r *TeamRepository) AddTeamsToLeague(leagueID int, teamIDs []int) error {
+// rulesColumns are the leagues columns holding the league rules, in the
+// order of rulesFields
+const rulesColumns = "points_win, points_draw, points_loss, bonus_points, bonus_goals, tie_breakers, standings_mode"
+
+// rulesFields returns the scan destinations for rulesColumns
+func rulesFields(rules *models.LeagueRules) []interface{} {
+	return []interface{}{&rules.PointsWin, &rules.PointsDraw, &rules.PointsLoss,
+		&rules.BonusPoints, &rules.BonusGoals, pq.Array(&rules.TieBreakers), &rules.StandingsMode}
+}
+
+// GetLeagues retrieves all leagues ordered by creation
+func (r *TeamRepository) GetLeagues(ctx context.Context) ([]models.League, error) {
+	rows, err := r.db().QueryContext(ctx, `
+		SELECT id, name, current_week, total_weeks, status, seed, engine, legs,
+		       rating_system, simulate_with_ratings, `+rulesColumns+`
+		FROM leagues ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query leagues: %v", err)
+	}
+	defer rows.Close()
+
+	leagues := []models.League{}
+	for rows.Next() {
+		var league models.League
+		fields := []interface{}{&league.ID, &league.Name, &league.CurrentWeek, &league.TotalWeeks,
+			&league.Status, &league.Seed, &league.Engine, &league.Legs,
+			&league.RatingSystem, &league.SimulateWithRatings}
+		err := rows.Scan(append(fields, rulesFields(&league.Rules)...)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan league: %v", err)
+		}
+		leagues = append(leagues, league)
+	}
+
+	return leagues, nil
+}
+
+// LeagueExists checks if a league exists by ID
+func (r *TeamRepository) LeagueExists(ctx context.Context, leagueID int) (bool, error) {
+	var exists bool
+	err := r.db().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM leagues WHERE id = $1)", leagueID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check league existence: %v", err)
+	}
+	return exists, nil
+}
+
+// GetLatestLeagueID returns the ID of the most recently created league, or 0 if there is none
+func (r *TeamRepository) GetLatestLeagueID(ctx context.Context) (int, error) {
+	var leagueID int
+	err := r.db().QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM leagues").Scan(&leagueID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest league: %v", err)
+	}
+	return leagueID, nil
+}
+
+// GetLeagueTeams retrieves the teams taking part in a league
+func (r *TeamRepository) GetLeagueTeams(ctx context.Context, leagueID int) ([]models.Team, error) {
+	rows, err := r.db().QueryContext(ctx, `
+		SELECT t.id, t.name, t.strength, t.attack, t.defence
+		FROM league_teams lt
+		JOIN teams t ON lt.team_id = t.id
+		WHERE lt.league_id = $1
+		ORDER BY t.name`,
+		leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query league teams: %v", err)
+	}
+	defer rows.Close()
+
+	var teams []models.Team
+	for rows.Next() {
+		var team models.Team
+		if err := rows.Scan(&team.ID, &team.Name, &team.Strength, &team.Attack, &team.Defence); err != nil {
+			return nil, fmt.Errorf("failed to scan team: %v", err)
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, nil
+}
+
+// addTeamsToLeague adds teams to a league
+func (r *TeamRepository) addTeamsToLeague(ctx context.Context, leagueID int, teamIDs []int) error {
 	for _, teamID := range teamIDs {
-		_, err := DB.Exec("INSERT INTO league_teams (league_id, team_id) VALUES ($1, $2)", 
+		_, err := r.db().ExecContext(ctx, "INSERT INTO league_teams (league_id, team_id) VALUES ($1, $2)",
 			leagueID, teamID)
 		if err != nil {
-			return fmt.Errorf("failed to add team to league: %v", err)
+			return classify(err, "failed to add team to league")
 		}
 	}
 	return nil
 }
 
-// InitializeTeamStats initializes team statistics for a league
-func (r *TeamRepository) InitializeTeamStats(leagueID int, teamIDs []int) error {
+// initializeTeamStats initializes team statistics for a league
+func (r *TeamRepository) initializeTeamStats(ctx context.Context, leagueID int, teamIDs []int) error {
 	for _, teamID := range teamIDs {
-		_, err := DB.Exec("INSERT INTO team_stats (league_id, team_id) VALUES ($1, $2)", 
+		_, err := r.db().ExecContext(ctx, "INSERT INTO team_stats (league_id, team_id) VALUES ($1, $2)",
 			leagueID, teamID)
 		if err != nil {
-			return fmt.Errorf("failed to initialize team stats: %v", err)
+			return classify(err, "failed to initialize team stats")
 		}
 	}
 	return nil
 }
 
-// SaveMatch saves a match result to the database
-func (r *TeamRepository) SaveMatch(leagueID int, match models.Match) error {
-	// Get team IDs by name
-	var homeTeamID, awayTeamID int
-	err := DB.QueryRow("SELECT id FROM teams WHERE name = $1", match.HomeTeam).Scan(&homeTeamID)
+// cardColumns are the matches columns holding the cards of a match, in the
+// order of cardFields
+const cardColumns = "m.home_yellow_cards, m.home_red_cards, m.away_yellow_cards, m.away_red_cards"
+
+// cardFields returns the scan destinations for cardColumns
+func cardFields(cards *models.MatchCards) []interface{} {
+	return []interface{}{&cards.HomeYellow, &cards.HomeRed, &cards.AwayYellow, &cards.AwayRed}
+}
+
+// SaveMatch records a match result on its scheduled fixture row
+func (r *TeamRepository) SaveMatch(ctx context.Context, leagueID int, match models.Match) error {
+	result, err := r.db().ExecContext(ctx, `
+		UPDATE matches SET home_score = $1, away_score = $2, played = true,
+			home_yellow_cards = $5, home_red_cards = $6, away_yellow_cards = $7, away_red_cards = $8
+		WHERE league_id = $3 AND id = $4 AND played = false`,
+		match.HomeScore, match.AwayScore, leagueID, match.ID,
+		match.Cards.HomeYellow, match.Cards.HomeRed, match.Cards.AwayYellow, match.Cards.AwayRed)
 	if err != nil {
-		return fmt.Errorf("failed to get home team ID: %v", err)
+		return fmt.Errorf("failed to save match: %v", err)
 	}
-	
-	err = DB.QueryRow("SELECT id FROM teams WHERE name = $1", match.AwayTeam).Scan(&awayTeamID)
+
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get away team ID: %v", err)
+		return fmt.Errorf("failed to get rows affected: %v", err)
 	}
 
-	// Insert match
-	_, err = DB.Exec(`
-		INSERT INTO matches (league_id, week_number, home_team_id, away_team_id, home_score, away_score, played) 
-		VALUES ($1, $2, $3, $4, $5, $6, true)`,
-		leagueID, match.Week, homeTeamID, awayTeamID, match.HomeScore, match.AwayScore)
-	
-	if err != nil {
-		return fmt.Errorf("failed to save match: %v", err)
+	if rowsAffected == 0 {
+		return conflictf("match with ID %d is not a scheduled fixture", match.ID)
 	}
 
 	return nil
 }
 
 // UpdateTeamStats updates team statistics after a match
-func (r *TeamRepository) UpdateTeamStats(leagueID int, teamName string, stats models.TeamStats) error {
+func (r *TeamRepository) UpdateTeamStats(ctx context.Context, leagueID int, teamName string, stats models.TeamStats) error {
 	// Try to update first, if no rows affected, insert
-	result, err := DB.Exec(`
+	result, err := r.db().ExecContext(ctx, `
 		UPDATE team_stats 
 		SET played = $1, won = $2, drawn = $3, lost = $4, 
 		    goals_for = $5, goals_against = $6, points = $7
@@ -102,42 +359,52 @@ func (r *TeamRepository) UpdateTeamStats(leagueID int, teamName string, stats mo
 		stats.Played, stats.Won, stats.Drawn, stats.Lost,
 		stats.GoalsFor, stats.GoalsAgainst, stats.Points,
 		leagueID, teamName)
-	
+
 	if err != nil {
-		return fmt.Errorf("failed to update team stats: %v", err)
+		return classify(err, "failed to update team stats")
 	}
-	
+
 	// Check if any rows were affected
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to get rows affected: %v", err)
 	}
-	
+
 	// If no rows were updated, insert a new record
 	if rowsAffected == 0 {
-		_, err = DB.Exec(`
+		_, err = r.db().ExecContext(ctx, `
 			INSERT INTO team_stats (league_id, team_id, played, won, drawn, lost, goals_for, goals_against, points)
 			VALUES ($1, (SELECT id FROM teams WHERE name = $2), $3, $4, $5, $6, $7, $8, $9)`,
 			leagueID, teamName, stats.Played, stats.Won, stats.Drawn, stats.Lost,
 			stats.GoalsFor, stats.GoalsAgainst, stats.Points)
-		
+
 		if err != nil {
-			return fmt.Errorf("failed to insert team stats: %v", err)
+			return classify(err, "failed to insert team stats")
 		}
 	}
-	
+
 	return nil
 }
 
-// GetLeagueTable retrieves the current league table
-func (r *TeamRepository) GetLeagueTable(leagueID int) ([]models.TeamStats, error) {
-	rows, err := DB.Query(`
+// GetLeagueTable retrieves the current league table, ranked by the league rules
+func (r *TeamRepository) GetLeagueTable(ctx context.Context, leagueID int) ([]models.TeamStats, error) {
+	var seed int64
+	var rules models.LeagueRules
+	err := r.db().QueryRowContext(ctx, "SELECT seed, "+rulesColumns+" FROM leagues WHERE id = $1", leagueID).
+		Scan(append([]interface{}{&seed}, rulesFields(&rules)...)...)
+	if err == sql.ErrNoRows {
+		return nil, nil // A league that does not exist has an empty table
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get league rules: %v", err)
+	}
+
+	rows, err := r.db().QueryContext(ctx, `
 		SELECT t.name, ts.played, ts.won, ts.drawn, ts.lost, 
 		       ts.goals_for, ts.goals_against, ts.points, ts.goal_difference
 		FROM team_stats ts
 		JOIN teams t ON ts.team_id = t.id
-		WHERE ts.league_id = $1
-		ORDER BY ts.points DESC, ts.goal_difference DESC, ts.goals_for DESC`,
+		WHERE ts.league_id = $1`,
 		leagueID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query league table: %v", err)
@@ -145,7 +412,6 @@ func (r *TeamRepository) GetLeagueTable(leagueID int) ([]models.TeamStats, error
 	defer rows.Close()
 
 	var table []models.TeamStats
-	position := 1
 	for rows.Next() {
 		var stats models.TeamStats
 		err := rows.Scan(&stats.TeamName, &stats.Played, &stats.Won, &stats.Drawn, &stats.Lost,
@@ -153,19 +419,25 @@ func (r *TeamRepository) GetLeagueTable(leagueID int) ([]models.TeamStats, error
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan team stats: %v", err)
 		}
-		stats.Position = position
-		
 		table = append(table, stats)
-		position++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read team stats: %v", err)
 	}
 
-	return table, nil
+	// Head-to-head and away goals tie-breakers need the results
+	played, err := r.GetMatches(ctx, leagueID)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankTable(table, played, rules, seed), nil
 }
 
 // GetMatches retrieves all matches for a league
-func (r *TeamRepository) GetMatches(leagueID int) ([]models.Match, error) {
-	rows, err := DB.Query(`
-		SELECT ht.name, at.name, m.home_score, m.away_score, m.week_number
+func (r *TeamRepository) GetMatches(ctx context.Context, leagueID int) ([]models.Match, error) {
+	rows, err := r.db().QueryContext(ctx, `
+		SELECT m.id, ht.name, at.name, m.home_score, m.away_score, m.week_number, `+cardColumns+`
 		FROM matches m
 		JOIN teams ht ON m.home_team_id = ht.id
 		JOIN teams at ON m.away_team_id = at.id
@@ -180,396 +452,481 @@ func (r *TeamRepository) GetMatches(leagueID int) ([]models.Match, error) {
 	var matches []models.Match
 	for rows.Next() {
 		var match models.Match
-		err := rows.Scan(&match.HomeTeam, &match.AwayTeam, &match.HomeScore, &match.AwayScore, &match.Week)
+		err := rows.Scan(append([]interface{}{&match.ID, &match.HomeTeam, &match.AwayTeam,
+			&match.HomeScore, &match.AwayScore, &match.Week}, cardFields(&match.Cards)...)...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan match: %v", err)
 		}
+		match.Status = models.MatchPlayed
 		matches = append(matches, match)
 	}
 
 	return matches, nil
 }
 
+// GetMatch retrieves a single match of a league
+func (r *TeamRepository) GetMatch(ctx context.Context, leagueID, matchID int) (*models.Match, error) {
+	var match models.Match
+	var homeScore, awayScore sql.NullInt64
+	var played bool
+	err := r.db().QueryRowContext(ctx, `
+		SELECT m.id, ht.name, at.name, m.home_score, m.away_score, m.week_number, m.played, `+cardColumns+`
+		FROM matches m
+		JOIN teams ht ON m.home_team_id = ht.id
+		JOIN teams at ON m.away_team_id = at.id
+		WHERE m.league_id = $1 AND m.id = $2`,
+		leagueID, matchID).Scan(append([]interface{}{&match.ID, &match.HomeTeam, &match.AwayTeam,
+		&homeScore, &awayScore, &match.Week, &played}, cardFields(&match.Cards)...)...)
+	if err == sql.ErrNoRows {
+		return nil, notFoundf("match with ID %d not found", matchID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get match: %v", err)
+	}
+	match.HomeScore = int(homeScore.Int64)
+	match.AwayScore = int(awayScore.Int64)
+	match.Status = matchStatus(played)
+	return &match, nil
+}
+
+// MatchExists checks if a match belongs to a league
+func (r *TeamRepository) MatchExists(ctx context.Context, leagueID, matchID int) (bool, error) {
+	var exists bool
+	err := r.db().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM matches WHERE league_id = $1 AND id = $2)",
+		leagueID, matchID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check match existence: %v", err)
+	}
+	return exists, nil
+}
+
+// SetMatchResult records or corrects the score of a match and recalculates the table
+func (r *TeamRepository) SetMatchResult(ctx context.Context, leagueID, matchID, homeScore, awayScore int, cards models.MatchCards) error {
+	return r.inLeagueTransaction(ctx, leagueID, func(txRepo *TeamRepository) error {
+		return txRepo.setMatchResult(ctx, leagueID, matchID, homeScore, awayScore, cards)
+	})
+}
+
+// setMatchResult updates the match row; callers hold the league lock
+func (r *TeamRepository) setMatchResult(ctx context.Context, leagueID, matchID, homeScore, awayScore int, cards models.MatchCards) error {
+	var week int
+	err := r.db().QueryRowContext(ctx, `
+		UPDATE matches SET home_score = $1, away_score = $2, played = true,
+			home_yellow_cards = $5, home_red_cards = $6, away_yellow_cards = $7, away_red_cards = $8
+		WHERE league_id = $3 AND id = $4
+		RETURNING week_number`,
+		homeScore, awayScore, leagueID, matchID,
+		cards.HomeYellow, cards.HomeRed, cards.AwayYellow, cards.AwayRed).Scan(&week)
+	if err == sql.ErrNoRows {
+		return notFoundf("match with ID %d not found", matchID)
+	}
+	if err != nil {
+		return classify(err, "failed to update match")
+	}
+
+	return r.recalculateTeamStats(ctx, leagueID, week)
+}
+
+// UnplayMatch clears the score of a match and recalculates the table and the
+// ratings from its week on. A match of a played week is played again with
+// the next week.
+func (r *TeamRepository) UnplayMatch(ctx context.Context, leagueID, matchID int) error {
+	return r.inLeagueTransaction(ctx, leagueID, func(txRepo *TeamRepository) error {
+		return txRepo.unplayMatch(ctx, leagueID, matchID)
+	})
+}
+
+// unplayMatch clears the match row; callers hold the league lock
+func (r *TeamRepository) unplayMatch(ctx context.Context, leagueID, matchID int) error {
+	match, err := r.GetMatch(ctx, leagueID, matchID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.unplayMatches(ctx, "m.league_id = $1 AND m.id = $2", leagueID, matchID); err != nil {
+		return err
+	}
+
+	return r.recalculateTeamStats(ctx, leagueID, match.Week)
+}
+
+// RewindLeague un-plays every match after the given week, restores the team
+// stats and moves the league back to that week. Fixtures are kept.
+func (r *TeamRepository) RewindLeague(ctx context.Context, leagueID, week int) error {
+	return r.inLeagueTransaction(ctx, leagueID, func(txRepo *TeamRepository) error {
+		return txRepo.rewindLeague(ctx, leagueID, week)
+	})
+}
+
+// rewindLeague does the work of RewindLeague; callers hold the league lock
+func (r *TeamRepository) rewindLeague(ctx context.Context, leagueID, week int) error {
+	if err := r.unplayMatches(ctx, "m.league_id = $1 AND m.week_number > $2", leagueID, week); err != nil {
+		return err
+	}
+
+	_, err := r.db().ExecContext(ctx, "UPDATE leagues SET current_week = $1, status = 'active' WHERE id = $2", week, leagueID)
+	if err != nil {
+		return fmt.Errorf("failed to update league week: %v", err)
+	}
+
+	return r.recalculateTeamStats(ctx, leagueID, week+1)
+}
+
+// unplayMatches turns the matches selected by the condition back into
+// scheduled fixtures by clearing their scores
+func (r *TeamRepository) unplayMatches(ctx context.Context, condition string, args ...interface{}) error {
+	_, err := r.db().ExecContext(ctx, `
+		UPDATE matches m SET home_score = NULL, away_score = NULL, played = false,
+			home_yellow_cards = 0, home_red_cards = 0, away_yellow_cards = 0, away_red_cards = 0
+		WHERE `+condition,
+		args...)
+	if err != nil {
+		return fmt.Errorf("failed to un-play matches: %v", err)
+	}
+
+	return nil
+}
+
+// RecalculateTeamStats rebuilds team_stats for a league from its played matches
+func (r *TeamRepository) RecalculateTeamStats(ctx context.Context, leagueID int) error {
+	return r.recalculateTeamStats(ctx, leagueID, 1)
+}
+
+// recalculateTeamStats does the work of RecalculateTeamStats. fromWeek is the
+// first week whose results changed; the ratings of the weeks before it are
+// kept.
+func (r *TeamRepository) recalculateTeamStats(ctx context.Context, leagueID, fromWeek int) error {
+	status, err := r.GetLeagueStatus(ctx, leagueID)
+	if err != nil {
+		return err
+	}
+
+	teams, err := r.GetLeagueTeams(ctx, leagueID)
+	if err != nil {
+		return err
+	}
+
+	played, err := r.GetMatches(ctx, leagueID)
+	if err != nil {
+		return err
+	}
+
+	// Replay every stored result into a fresh table
+	league := services.NewGenerateLeague(teams)
+	league.Rules = *status.Rules
+	league.ApplyResults(played)
+
+	for _, team := range teams {
+		if err := r.UpdateTeamStats(ctx, leagueID, team.Name, toModelStats(league.TeamStats[team.Name])); err != nil {
+			return err
+		}
+	}
+
+	// A changed result moves the ratings of its week and every played week
+	// after it
+	if status.RatingSystem == services.RatingsElo {
+		return r.storeRatingHistory(ctx, leagueID, min(fromWeek-1, status.CurrentWeek), status.CurrentWeek)
+	}
+
+	return nil
+}
+
+// storeRatingHistory works out the ratings after each week from fromWeek to
+// toWeek, starting from the stored ones after fromWeek, and replaces those
+// weeks and any later ones. Earlier weeks are kept as they were stored.
+func (r *TeamRepository) storeRatingHistory(ctx context.Context, leagueID, fromWeek, toWeek int) error {
+	history, err := r.GetRatingHistory(ctx, leagueID)
+	if err != nil {
+		return err
+	}
+	teams, err := r.GetLeagueTeams(ctx, leagueID)
+	if err != nil {
+		return err
+	}
+	played, err := r.GetMatches(ctx, leagueID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db().ExecContext(ctx, "DELETE FROM team_ratings WHERE league_id = $1 AND week_number >= $2", leagueID, fromWeek)
+	if err != nil {
+		return fmt.Errorf("failed to clear ratings: %v", err)
+	}
+
+	teamIDs := make(map[string]int)
+	for _, team := range teams {
+		teamIDs[team.Name] = team.ID
+	}
+
+	start := services.RatingsAfter(history, fromWeek)
+	for _, rating := range services.RatingHistory(teams, start, fromWeek, toWeek, played) {
+		_, err := r.db().ExecContext(ctx, `
+			INSERT INTO team_ratings (league_id, team_id, week_number, rating)
+			VALUES ($1, $2, $3, $4)`,
+			leagueID, teamIDs[rating.TeamName], rating.Week, rating.Rating)
+		if err != nil {
+			return classify(err, "failed to store rating")
+		}
+	}
+
+	return nil
+}
+
+// GetRatingHistory retrieves the rating of every team of a league after each week
+func (r *TeamRepository) GetRatingHistory(ctx context.Context, leagueID int) ([]models.TeamRating, error) {
+	rows, err := r.db().QueryContext(ctx, `
+		SELECT t.name, tr.week_number, tr.rating
+		FROM team_ratings tr
+		JOIN teams t ON tr.team_id = t.id
+		WHERE tr.league_id = $1
+		ORDER BY tr.week_number, t.name`,
+		leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ratings: %v", err)
+	}
+	defer rows.Close()
+
+	history := []models.TeamRating{}
+	for rows.Next() {
+		var rating models.TeamRating
+		if err := rows.Scan(&rating.TeamName, &rating.Week, &rating.Rating); err != nil {
+			return nil, fmt.Errorf("failed to scan rating: %v", err)
+		}
+		history = append(history, rating)
+	}
+
+	return history, rows.Err()
+}
+
 // GetLeagueStatus retrieves the current league status
-func (r *TeamRepository) GetLeagueStatus(leagueID int) (models.LeagueResponse, error) {
+func (r *TeamRepository) GetLeagueStatus(ctx context.Context, leagueID int) (models.LeagueResponse, error) {
 	var response models.LeagueResponse
-	
-	err := DB.QueryRow(`
-		SELECT current_week, total_weeks, status
-		FROM leagues WHERE id = $1`, leagueID).Scan(&response.CurrentWeek, &response.TotalWeeks, &response.Status)
+	var rules models.LeagueRules
+
+	fields := []interface{}{&response.Name, &response.CurrentWeek, &response.TotalWeeks,
+		&response.Status, &response.Seed, &response.Engine, &response.Legs,
+		&response.RatingSystem, &response.SimulateWithRatings}
+	err := r.db().QueryRowContext(ctx, `
+		SELECT name, current_week, total_weeks, status, seed, engine, legs,
+		       rating_system, simulate_with_ratings, `+rulesColumns+`
+		FROM leagues WHERE id = $1`, leagueID).Scan(append(fields, rulesFields(&rules)...)...)
+	if err == sql.ErrNoRows {
+		return response, notFoundf("league with ID %d not found", leagueID)
+	}
 	if err != nil {
 		return response, fmt.Errorf("failed to get league status: %v", err)
 	}
-	
+	response.Rules = &rules
+
 	// Calculate progress percentage
 	if response.TotalWeeks > 0 {
 		progress := float64(response.CurrentWeek) / float64(response.TotalWeeks) * 100
 		response.Progress = fmt.Sprintf("%.1f%%", progress)
 	}
-	
+
 	return response, nil
 }
 
 // ClearLeague clears all data for a league
-func (r *TeamRepository) ClearLeague(leagueID int) error {
+func (r *TeamRepository) ClearLeague(ctx context.Context, leagueID int) error {
 	// Start a transaction
-	tx, err := DB.Begin()
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback() // Rollback if not committed
-	
+
 	// Delete matches
-	_, err = tx.Exec("DELETE FROM matches WHERE league_id = $1", leagueID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM matches WHERE league_id = $1", leagueID)
 	if err != nil {
 		return fmt.Errorf("failed to delete matches: %v", err)
 	}
-	
+
 	// Delete team stats
-	_, err = tx.Exec("DELETE FROM team_stats WHERE league_id = $1", leagueID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM team_stats WHERE league_id = $1", leagueID)
 	if err != nil {
 		return fmt.Errorf("failed to delete team stats: %v", err)
 	}
-	
+
+	// Delete rating history
+	_, err = tx.ExecContext(ctx, "DELETE FROM team_ratings WHERE league_id = $1", leagueID)
+	if err != nil {
+		return fmt.Errorf("failed to delete ratings: %v", err)
+	}
+
 	// Delete league_teams associations
-	_, err = tx.Exec("DELETE FROM league_teams WHERE league_id = $1", leagueID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM league_teams WHERE league_id = $1", leagueID)
 	if err != nil {
 		return fmt.Errorf("failed to delete league teams: %v", err)
 	}
-	
+
 	// Delete the league itself
-	_, err = tx.Exec("DELETE FROM leagues WHERE id = $1", leagueID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM leagues WHERE id = $1", leagueID)
 	if err != nil {
 		return fmt.Errorf("failed to delete league: %v", err)
 	}
-	
+
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
-	
+
 	return nil
-} 
+}
 
-// PlayWeek plays a single week for a league
-func (r *TeamRepository) PlayWeek(leagueID int) ([]models.Match, error) {
-	// Get current week
-	var currentWeek int
-	err := DB.QueryRow("SELECT current_week FROM leagues WHERE id = $1", leagueID).Scan(&currentWeek)
+// PlayWeek plays a single week for a league inside one transaction. The
+// league row is locked, so concurrent calls play consecutive weeks instead
+// of the same week twice.
+func (r *TeamRepository) PlayWeek(ctx context.Context, leagueID int) ([]models.Match, error) {
+	var weekMatches []models.Match
+	err := r.inLeagueTransaction(ctx, leagueID, func(txRepo *TeamRepository) error {
+		var err error
+		weekMatches, err = txRepo.playWeek(ctx, leagueID)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current week: %v", err)
+		return nil, err
 	}
-	
-	// Get total weeks
-	var totalWeeks int
-	err = DB.QueryRow("SELECT total_weeks FROM leagues WHERE id = $1", leagueID).Scan(&totalWeeks)
+	return weekMatches, nil
+}
+
+// LoadSimulation restores the simulation of a league as PlayWeek would and
+// returns it with the fixtures still to be played. Everything is read under
+// the league lock, so a week played at the same time is either all in or all
+// out.
+func (r *TeamRepository) LoadSimulation(ctx context.Context, leagueID int) (*services.GenerateLeague, [][]models.Match, error) {
+	var league *services.GenerateLeague
+	err := r.inLeagueTransaction(ctx, leagueID, func(txRepo *TeamRepository) error {
+		var err error
+		_, league, err = txRepo.restoreSimulation(ctx, leagueID)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get total weeks: %v", err)
+		return nil, nil, err
 	}
-	
-	// Check if season is complete
-	if currentWeek >= totalWeeks {
-		return nil, fmt.Errorf("season is complete, no more weeks to play")
+	return league, remainingFixtures(league), nil
+}
+
+// restoreSimulation reads a stored league and rebuilds its simulation;
+// callers hold the league lock
+func (r *TeamRepository) restoreSimulation(ctx context.Context, leagueID int) (models.League, *services.GenerateLeague, error) {
+	// Get current week, total weeks, the league seed, its match engine, format, ratings and rules
+	var stored models.League
+	fields := []interface{}{&stored.CurrentWeek, &stored.TotalWeeks, &stored.Seed, &stored.Engine, &stored.Legs,
+		&stored.RatingSystem, &stored.SimulateWithRatings}
+	err := r.db().QueryRowContext(ctx, `
+		SELECT current_week, total_weeks, seed, engine, legs, rating_system, simulate_with_ratings, `+rulesColumns+`
+		FROM leagues WHERE id = $1`, leagueID).
+		Scan(append(fields, rulesFields(&stored.Rules)...)...)
+	if err == sql.ErrNoRows {
+		return stored, nil, notFoundf("league with ID %d not found", leagueID)
 	}
-	
-	// Get all teams for the league
-	teams, err := r.GetAllTeams()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get teams: %v", err)
+		return stored, nil, fmt.Errorf("failed to get league: %v", err)
 	}
-	
-	// Create in-memory league for simulation
-	league := services.NewGenerateLeague(teams)
-	
-	// Check if fixtures exist in database, if not, store them
-	var fixtureCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM matches WHERE league_id = $1", leagueID).Scan(&fixtureCount)
+	stored.ID = leagueID
+
+	teams, err := r.GetLeagueTeams(ctx, leagueID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check fixtures: %v", err)
+		return stored, nil, fmt.Errorf("failed to get teams: %v", err)
 	}
-	
-	if fixtureCount == 0 {
-		// Store fixtures in database
-		if err := r.StoreFixtures(leagueID, league.Fixtures); err != nil {
-			return nil, fmt.Errorf("failed to store fixtures: %v", err)
-		}
-	} else {
-		// Load fixtures from database
-		fixtures, err := r.GetMatchSchedule(leagueID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load fixtures: %v", err)
-		}
-		
-		// Convert map to slice format expected by league
-		var fixturesSlice [][]models.Match
-		for week := 1; week <= len(fixtures); week++ {
-			if weekMatches, exists := fixtures[week]; exists {
-				fixturesSlice = append(fixturesSlice, weekMatches)
-			}
-		}
-		league.Fixtures = fixturesSlice
-		
-		// Load existing matches and stats from database
-		existingMatches, err := r.GetMatches(leagueID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get existing matches: %v", err)
-		}
-		
-		// Add existing matches to the league
-		for _, match := range existingMatches {
-			league.Results = append(league.Results, match)
-		}
-		
-		// Load existing team stats from database
-		existingStats, err := r.GetLeagueTable(leagueID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get existing stats: %v", err)
-		}
-		
-		// Update in-memory stats with database stats
-		for _, stat := range existingStats {
-			if leagueStat, exists := league.TeamStats[stat.TeamName]; exists {
-				leagueStat.Played = stat.Played
-				leagueStat.Won = stat.Won
-				leagueStat.Drawn = stat.Drawn
-				leagueStat.Lost = stat.Lost
-				leagueStat.GoalsFor = stat.GoalsFor
-				leagueStat.GoalsAgainst = stat.GoalsAgainst
-				leagueStat.Points = stat.Points
-				leagueStat.GoalDiff = stat.GoalDiff
 
-			}
-		}
+	// Load fixtures from database so every match keeps its row ID
+	schedule, err := r.GetMatchSchedule(ctx, leagueID)
+	if err != nil {
+		return stored, nil, fmt.Errorf("failed to load fixtures: %v", err)
 	}
-	
-	// Set the current week to match the database
-	league.CurrentWeek = currentWeek
-	
-	// Play only the next week
-	nextWeek := currentWeek + 1
-	
-	// Safety check to prevent index out of range
-	if nextWeek-1 >= len(league.Fixtures) {
-		return nil, fmt.Errorf("no fixtures available for week %d", nextWeek)
-	}
-	
-	weekFixtures := league.Fixtures[nextWeek-1] // week-1 because fixtures are 0-indexed
-	
-	var weekMatches []models.Match
-	
-	// Play each match in this week
-	for _, fixture := range weekFixtures {
-		// Find the actual team objects
-		var homeTeam, awayTeam models.Team
-		for _, team := range teams {
-			if team.Name == fixture.HomeTeam {
-				homeTeam = team
-			}
-			if team.Name == fixture.AwayTeam {
-				awayTeam = team
-			}
-		}
-		
-		// Play the match
-		match, err := services.PlayMatch(homeTeam, awayTeam, league)
-		if err != nil {
-			return nil, fmt.Errorf("failed to play match: %v", err)
-		}
-		
-		// Set the week number
-		match.Week = nextWeek
-		
-		// Save match to database
-		if err := r.SaveMatch(leagueID, match); err != nil {
-			return nil, fmt.Errorf("failed to save match: %v", err)
-		}
-		
-		// Update team stats
-		homeStats := league.TeamStats[match.HomeTeam]
-		if homeStats != nil {
-			// Convert services.TeamStats to models.TeamStats
-			modelStats := models.TeamStats{
-				TeamName:     homeStats.TeamName,
-				Played:       homeStats.Played,
-				Won:          homeStats.Won,
-				Drawn:        homeStats.Drawn,
-				Lost:         homeStats.Lost,
-				GoalsFor:     homeStats.GoalsFor,
-				GoalsAgainst: homeStats.GoalsAgainst,
-				Points:       homeStats.Points,
-				GoalDiff:     homeStats.GoalDiff,
-			}
-
-			if err := r.UpdateTeamStats(leagueID, match.HomeTeam, modelStats); err != nil {
-				return nil, fmt.Errorf("failed to update home team stats: %v", err)
-			}
-		}
-		
-		awayStats := league.TeamStats[match.AwayTeam]
-		if awayStats != nil {
-			// Convert services.TeamStats to models.TeamStats
-			modelStats := models.TeamStats{
-				TeamName:     awayStats.TeamName,
-				Played:       awayStats.Played,
-				Won:          awayStats.Won,
-				Drawn:        awayStats.Drawn,
-				Lost:         awayStats.Lost,
-				GoalsFor:     awayStats.GoalsFor,
-				GoalsAgainst: awayStats.GoalsAgainst,
-				Points:       awayStats.Points,
-				GoalDiff:     awayStats.GoalDiff,
-			}
 
-			if err := r.UpdateTeamStats(leagueID, match.AwayTeam, modelStats); err != nil {
-				return nil, fmt.Errorf("failed to update away team stats: %v", err)
-			}
-		}
-		
-		weekMatches = append(weekMatches, match)
-	}
-	
-	// Update league current week
-	_, err = DB.Exec("UPDATE leagues SET current_week = $1 WHERE id = $2", nextWeek, leagueID)
+	played, err := r.GetMatches(ctx, leagueID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update league week: %v", err)
+		return stored, nil, fmt.Errorf("failed to get existing matches: %v", err)
 	}
-	
-	return weekMatches, nil
-}
 
-// PlayAllWeeks plays all remaining weeks in the league
-func (r *TeamRepository) PlayAllWeeks(leagueID int) ([]models.Match, error) {
-	// Get total weeks for the league
-	var totalWeeks int
-	err := DB.QueryRow("SELECT total_weeks FROM leagues WHERE id = $1", leagueID).Scan(&totalWeeks)
+	standings, err := r.GetLeagueTable(ctx, leagueID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get total weeks: %v", err)
+		return stored, nil, fmt.Errorf("failed to get existing stats: %v", err)
 	}
-	
-	// Get current week
-	var currentWeek int
-	err = DB.QueryRow("SELECT current_week FROM leagues WHERE id = $1", leagueID).Scan(&currentWeek)
+
+	ratings, err := r.GetRatingHistory(ctx, leagueID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current week: %v", err)
+		return stored, nil, fmt.Errorf("failed to get ratings: %v", err)
 	}
-	
-	// Get all teams for the league
-	teams, err := r.GetAllTeams()
+
+	league, err := restoreLeague(teams, stored, schedule, played, standings, ratings)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get teams: %v", err)
+		return stored, nil, err
 	}
-	
-	// Create in-memory league for simulation
-	league := services.NewGenerateLeague(teams)
-	
-	// Check if fixtures exist in database, if not, store them
-	var fixtureCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM matches WHERE league_id = $1", leagueID).Scan(&fixtureCount)
+	return stored, league, nil
+}
+
+// playWeek simulates and stores the next week; callers hold the league lock
+func (r *TeamRepository) playWeek(ctx context.Context, leagueID int) ([]models.Match, error) {
+	stored, league, err := r.restoreSimulation(ctx, leagueID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check fixtures: %v", err)
+		return nil, err
 	}
-	
-	if fixtureCount == 0 {
-		// Store fixtures in database
-		if err := r.StoreFixtures(leagueID, league.Fixtures); err != nil {
+
+	// Leagues created without fixtures get them when the first week is played
+	if len(league.Fixtures) == 0 {
+		if err := r.storeFixtures(ctx, leagueID, services.GenerateFixtureWithLegs(league.Teams, stored.Legs)); err != nil {
 			return nil, fmt.Errorf("failed to store fixtures: %v", err)
 		}
+		if stored, league, err = r.restoreSimulation(ctx, leagueID); err != nil {
+			return nil, err
+		}
+	}
+
+	nextWeek, err := nextWeekToPlay(league, stored.TotalWeeks)
+	if err != nil {
+		return nil, err
+	}
+	weekMatches, err := simulateWeek(league, league.Teams, nextWeek)
+	if err != nil {
+		return nil, err
 	}
-	
-	// Play all remaining weeks
-	var allMatches []models.Match
-	for week := currentWeek + 1; week <= totalWeeks; week++ {
-		// Safety check to prevent index out of range
-		if week-1 >= len(league.Fixtures) {
-			return nil, fmt.Errorf("no fixtures available for week %d", week)
+
+	for _, match := range weekMatches {
+		// Save match to database
+		if err := r.SaveMatch(ctx, leagueID, match); err != nil {
+			return nil, fmt.Errorf("failed to save match: %v", err)
 		}
-		
-		// Get the fixtures for this week
-		weekFixtures := league.Fixtures[week-1] // week-1 because fixtures are 0-indexed
-		
-		// Play each match in this week
-		for _, fixture := range weekFixtures {
-			// Find the actual team objects
-			var homeTeam, awayTeam models.Team
-			for _, team := range teams {
-				if team.Name == fixture.HomeTeam {
-					homeTeam = team
-				}
-				if team.Name == fixture.AwayTeam {
-					awayTeam = team
-				}
-			}
-			
-			// Play the match
-			match, err := services.PlayMatch(homeTeam, awayTeam, league)
-			if err != nil {
-				return nil, fmt.Errorf("failed to play match: %v", err)
-			}
-			
-			// Set the week number
-			match.Week = week
-			
-			// Save match to database
-			if err := r.SaveMatch(leagueID, match); err != nil {
-				return nil, fmt.Errorf("failed to save match: %v", err)
-			}
-			
-			// Update team stats
-			homeStats := league.TeamStats[match.HomeTeam]
-			if homeStats != nil {
-				// Convert services.TeamStats to models.TeamStats
-				modelStats := models.TeamStats{
-					TeamName:     homeStats.TeamName,
-					Played:       homeStats.Played,
-					Won:          homeStats.Won,
-					Drawn:        homeStats.Drawn,
-					Lost:         homeStats.Lost,
-					GoalsFor:     homeStats.GoalsFor,
-					GoalsAgainst: homeStats.GoalsAgainst,
-					Points:       homeStats.Points,
-					GoalDiff:     homeStats.GoalDiff,
-				}
-				if err := r.UpdateTeamStats(leagueID, match.HomeTeam, modelStats); err != nil {
-					return nil, fmt.Errorf("failed to update home team stats: %v", err)
-				}
-			}
-			
-			awayStats := league.TeamStats[match.AwayTeam]
-			if awayStats != nil {
-				// Convert services.TeamStats to models.TeamStats
-				modelStats := models.TeamStats{
-					TeamName:     awayStats.TeamName,
-					Played:       awayStats.Played,
-					Won:          awayStats.Won,
-					Drawn:        awayStats.Drawn,
-					Lost:         awayStats.Lost,
-					GoalsFor:     awayStats.GoalsFor,
-					GoalsAgainst: awayStats.GoalsAgainst,
-					Points:       awayStats.Points,
-					GoalDiff:     awayStats.GoalDiff,
-				}
-				if err := r.UpdateTeamStats(leagueID, match.AwayTeam, modelStats); err != nil {
-					return nil, fmt.Errorf("failed to update away team stats: %v", err)
-				}
-			}
-			
-			allMatches = append(allMatches, match)
+
+		// Update team stats
+		if err := r.UpdateTeamStats(ctx, leagueID, match.HomeTeam, toModelStats(league.TeamStats[match.HomeTeam])); err != nil {
+			return nil, fmt.Errorf("failed to update home team stats: %v", err)
+		}
+		if err := r.UpdateTeamStats(ctx, leagueID, match.AwayTeam, toModelStats(league.TeamStats[match.AwayTeam])); err != nil {
+			return nil, fmt.Errorf("failed to update away team stats: %v", err)
+		}
+	}
+
+	// The week's ratings follow on from the stored ones of the week before
+	if stored.RatingSystem == services.RatingsElo {
+		if err := r.storeRatingHistory(ctx, leagueID, firstRatedWeek(weekMatches, nextWeek)-1, nextWeek); err != nil {
+			return nil, err
 		}
 	}
-	
-	// Update league current week to total weeks
-	_, err = DB.Exec("UPDATE leagues SET current_week = $1 WHERE id = $2", totalWeeks, leagueID)
+
+	// Update league current week
+	_, err = r.db().ExecContext(ctx, "UPDATE leagues SET current_week = $1 WHERE id = $2", nextWeek, leagueID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update league week: %v", err)
 	}
-	
-	return allMatches, nil
-} 
+
+	return weekMatches, nil
+}
+
+// PlayAllWeeks plays all remaining weeks in the league one week at a time,
+// so the results are identical to playing them individually. Every week is
+// its own transaction.
+func (r *TeamRepository) PlayAllWeeks(ctx context.Context, leagueID int) ([]models.Match, error) {
+	return playAllWeeks(ctx, r, leagueID)
+}
 
 // GetMatchesByWeek retrieves matches for a specific week
-func (r *TeamRepository) GetMatchesByWeek(leagueID int, weekNumber int) ([]models.Match, error) {
-	rows, err := DB.Query(`
-		SELECT ht.name, at.name, m.home_score, m.away_score, m.week_number
+func (r *TeamRepository) GetMatchesByWeek(ctx context.Context, leagueID int, weekNumber int) ([]models.Match, error) {
+	rows, err := r.db().QueryContext(ctx, `
+		SELECT m.id, ht.name, at.name, m.home_score, m.away_score, m.week_number, `+cardColumns+`
 		FROM matches m
 		JOIN teams ht ON m.home_team_id = ht.id
 		JOIN teams at ON m.away_team_id = at.id
@@ -584,222 +941,184 @@ func (r *TeamRepository) GetMatchesByWeek(leagueID int, weekNumber int) ([]model
 	var matches []models.Match
 	for rows.Next() {
 		var match models.Match
-		err := rows.Scan(&match.HomeTeam, &match.AwayTeam, &match.HomeScore, &match.AwayScore, &match.Week)
+		err := rows.Scan(append([]interface{}{&match.ID, &match.HomeTeam, &match.AwayTeam,
+			&match.HomeScore, &match.AwayScore, &match.Week}, cardFields(&match.Cards)...)...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan match: %v", err)
 		}
+		match.Status = models.MatchPlayed
 		matches = append(matches, match)
 	}
 
 	return matches, nil
-} 
-
-// InitializeDatabase runs the schema.sql to set up the database
-func (r *TeamRepository) InitializeDatabase() error {
-	// First clear existing data in correct order (respecting foreign key constraints)
-	_, err := DB.Exec("DELETE FROM matches")
-	if err != nil {
-		return fmt.Errorf("failed to clear existing matches: %v", err)
-	}
-	
-	_, err = DB.Exec("DELETE FROM team_stats")
-	if err != nil {
-		return fmt.Errorf("failed to clear existing team stats: %v", err)
-	}
-	
-	_, err = DB.Exec("DELETE FROM league_teams")
-	if err != nil {
-		return fmt.Errorf("failed to clear existing league teams: %v", err)
-	}
-	
-	_, err = DB.Exec("DELETE FROM leagues")
-	if err != nil {
-		return fmt.Errorf("failed to clear existing leagues: %v", err)
-	}
-	
-	_, err = DB.Exec("DELETE FROM teams")
-	if err != nil {
-		return fmt.Errorf("failed to clear existing teams: %v", err)
-	}
-	
-	// Read and execute the schema.sql file
-	schemaSQL := `
-	-- Teams table
-	CREATE TABLE IF NOT EXISTS teams (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(100) NOT NULL UNIQUE,
-		strength INTEGER NOT NULL CHECK (strength >= 1 AND strength <= 100)
-	);
-
-	-- Leagues table  
-	CREATE TABLE IF NOT EXISTS leagues (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(100) NOT NULL,
-		current_week INTEGER DEFAULT 0,
-		total_weeks INTEGER NOT NULL,
-		status VARCHAR(20) DEFAULT 'active' CHECK (status IN ('active', 'completed', 'paused'))
-	);
-
-	-- League teams (many-to-many relationship)
-	CREATE TABLE IF NOT EXISTS league_teams (
-		id SERIAL PRIMARY KEY,
-		league_id INTEGER REFERENCES leagues(id) ON DELETE CASCADE,
-		team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
-		UNIQUE(league_id, team_id)
-	);
-
-	-- Matches table
-	CREATE TABLE IF NOT EXISTS matches (
-		id SERIAL PRIMARY KEY,
-		league_id INTEGER REFERENCES leagues(id) ON DELETE CASCADE,
-		week_number INTEGER NOT NULL,
-		home_team_id INTEGER REFERENCES teams(id),
-		away_team_id INTEGER REFERENCES teams(id),
-		home_score INTEGER DEFAULT NULL,
-		away_score INTEGER DEFAULT NULL,
-		played BOOLEAN DEFAULT FALSE,
-		played_at INTEGER DEFAULT NULL,
-		CHECK (home_team_id != away_team_id)
-	);
-
-	-- Team statistics table
-	CREATE TABLE IF NOT EXISTS team_stats (
-		id SERIAL PRIMARY KEY,
-		league_id INTEGER REFERENCES leagues(id) ON DELETE CASCADE,
-		team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
-		played INTEGER DEFAULT 0,
-		won INTEGER DEFAULT 0,
-		drawn INTEGER DEFAULT 0,
-		lost INTEGER DEFAULT 0,
-		goals_for INTEGER DEFAULT 0,
-		goals_against INTEGER DEFAULT 0,
-		points INTEGER DEFAULT 0,
-		goal_difference INTEGER GENERATED ALWAYS AS (goals_for - goals_against) STORED,
-		UNIQUE(league_id, team_id)
-	);
-
-	-- Insert sample teams (4 teams for smaller league)
-	INSERT INTO teams (name, strength) VALUES 
-    ('Arsenal', 70),
-    ('Chelsea', 85),
-    ('Liverpool', 75),
-    ('Manchester City', 92);
-	`
-	
-	_, err = DB.Exec(schemaSQL)
-	return err
+}
+
+// ResetDatabase removes all teams and leagues and restores the sample teams.
+// The schema itself is managed by Migrate.
+func (r *TeamRepository) ResetDatabase(ctx context.Context) error {
+	// Start a transaction
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback() // Rollback if not committed
+
+	// Clear existing data in correct order (respecting foreign key constraints)
+	for _, table := range []string{"matches", "team_stats", "team_ratings", "league_teams", "leagues", "teams"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			return fmt.Errorf("failed to clear existing %s: %v", table, err)
+		}
+	}
+
+	if err := seedSampleTeams(ctx, tx); err != nil {
+		return err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
 }
 
 // AddTeam adds a new team to the database
-func (r *TeamRepository) AddTeam(name string, strength int) error {
-	_, err := DB.Exec("INSERT INTO teams (name, strength) VALUES ($1, $2)", name, strength)
-	return err
+func (r *TeamRepository) AddTeam(ctx context.Context, name string, strength int) error {
+	_, err := r.db().ExecContext(ctx, "INSERT INTO teams (name, strength) VALUES ($1, $2)", name, strength)
+	if err != nil {
+		return classify(err, "failed to add team")
+	}
+	return nil
 }
 
 // AddTeamWithID adds a new team and returns the created team with ID
-func (r *TeamRepository) AddTeamWithID(name string, strength int) (*models.Team, error) {
-	var team models.Team
-	err := DB.QueryRow("INSERT INTO teams (name, strength) VALUES ($1, $2) RETURNING id, name, strength", 
-		name, strength).Scan(&team.ID, &team.Name, &team.Strength)
+func (r *TeamRepository) AddTeamWithID(ctx context.Context, team models.Team) (*models.Team, error) {
+	err := r.db().QueryRowContext(ctx, `
+		INSERT INTO teams (name, strength, attack, defence) VALUES ($1, $2, $3, $4)
+		RETURNING id, name, strength, attack, defence`,
+		team.Name, team.Strength, team.Attack, team.Defence).Scan(&team.ID, &team.Name, &team.Strength, &team.Attack, &team.Defence)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add team: %v", err)
+		return nil, classify(err, "failed to add team")
 	}
 	return &team, nil
 }
 
 // GetTeamByID retrieves a team by ID
-func (r *TeamRepository) GetTeamByID(id int) (*models.Team, error) {
+func (r *TeamRepository) GetTeamByID(ctx context.Context, id int) (*models.Team, error) {
 	var team models.Team
-	err := DB.QueryRow("SELECT id, name, strength FROM teams WHERE id = $1", id).Scan(&team.ID, &team.Name, &team.Strength)
+	err := r.db().QueryRowContext(ctx, "SELECT id, name, strength, attack, defence FROM teams WHERE id = $1", id).Scan(&team.ID, &team.Name, &team.Strength, &team.Attack, &team.Defence)
 	if err != nil {
-		return nil, fmt.Errorf("team not found: %v", err)
+		if err == sql.ErrNoRows {
+			return nil, notFoundf("team with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get team: %v", err)
 	}
 	return &team, nil
 }
 
 // GetTeamByName retrieves a team by name
-func (r *TeamRepository) GetTeamByName(name string) (*models.Team, error) {
+func (r *TeamRepository) GetTeamByName(ctx context.Context, name string) (*models.Team, error) {
 	var team models.Team
-	err := DB.QueryRow("SELECT id, name, strength FROM teams WHERE name = $1", name).Scan(&team.ID, &team.Name, &team.Strength)
+	err := r.db().QueryRowContext(ctx, "SELECT id, name, strength, attack, defence FROM teams WHERE name = $1", name).Scan(&team.ID, &team.Name, &team.Strength, &team.Attack, &team.Defence)
 	if err != nil {
-		return nil, fmt.Errorf("team not found: %v", err)
+		if err == sql.ErrNoRows {
+			return nil, notFoundf("team %q not found", name)
+		}
+		return nil, fmt.Errorf("failed to get team: %v", err)
 	}
 	return &team, nil
 }
 
 // UpdateTeam updates an existing team
-func (r *TeamRepository) UpdateTeam(id int, name string, strength int) error {
-	result, err := DB.Exec("UPDATE teams SET name = $1, strength = $2 WHERE id = $3", name, strength, id)
+func (r *TeamRepository) UpdateTeam(ctx context.Context, team models.Team) error {
+	result, err := r.db().ExecContext(ctx, "UPDATE teams SET name = $1, strength = $2, attack = $3, defence = $4 WHERE id = $5",
+		team.Name, team.Strength, team.Attack, team.Defence, team.ID)
 	if err != nil {
-		return fmt.Errorf("failed to update team: %v", err)
+		return classify(err, "failed to update team")
 	}
-	
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to get rows affected: %v", err)
 	}
-	
+
 	if rowsAffected == 0 {
-		return fmt.Errorf("team with ID %d not found", id)
+		return notFoundf("team with ID %d not found", team.ID)
 	}
-	
+
 	return nil
 }
 
 // DeleteTeam deletes a team by ID
-func (r *TeamRepository) DeleteTeam(id int) error {
+func (r *TeamRepository) DeleteTeam(ctx context.Context, id int) error {
 	// Start a transaction
-	tx, err := DB.Begin()
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback() // Rollback if not committed
-	
+
 	// Delete related matches first (both home and away)
-	_, err = tx.Exec("DELETE FROM matches WHERE home_team_id = $1 OR away_team_id = $1", id)
+	_, err = tx.ExecContext(ctx, "DELETE FROM matches WHERE home_team_id = $1 OR away_team_id = $1", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete matches: %v", err)
 	}
-	
+
 	// Delete team stats (this should cascade automatically, but being explicit)
-	_, err = tx.Exec("DELETE FROM team_stats WHERE team_id = $1", id)
+	_, err = tx.ExecContext(ctx, "DELETE FROM team_stats WHERE team_id = $1", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete team stats: %v", err)
 	}
-	
+
+	// Delete rating history (this should cascade automatically, but being explicit)
+	_, err = tx.ExecContext(ctx, "DELETE FROM team_ratings WHERE team_id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete ratings: %v", err)
+	}
+
 	// Delete league_teams associations (this should cascade automatically, but being explicit)
-	_, err = tx.Exec("DELETE FROM league_teams WHERE team_id = $1", id)
+	_, err = tx.ExecContext(ctx, "DELETE FROM league_teams WHERE team_id = $1", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete league teams: %v", err)
 	}
-	
+
 	// Finally delete the team
-	result, err := tx.Exec("DELETE FROM teams WHERE id = $1", id)
+	result, err := tx.ExecContext(ctx, "DELETE FROM teams WHERE id = $1", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete team: %v", err)
 	}
-	
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to get rows affected: %v", err)
 	}
-	
+
 	if rowsAffected == 0 {
-		return fmt.Errorf("team with ID %d not found", id)
+		return notFoundf("team with ID %d not found", id)
 	}
-	
+
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
-	
+
 	return nil
 }
 
 // TeamExists checks if a team exists by name
-func (r *TeamRepository) TeamExists(name string) (bool, error) {
+func (r *TeamRepository) TeamExists(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	err := r.db().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE name = $1)", name).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check team existence: %v", err)
+	}
+	return exists, nil
+}
+
+// TeamExistsByID checks if a team exists by ID
+func (r *TeamRepository) TeamExistsByID(ctx context.Context, id int) (bool, error) {
 	var exists bool
-	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM teams WHERE name = $1)", name).Scan(&exists)
+	err := r.db().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE id = $1)", id).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check team existence: %v", err)
 	}
@@ -807,9 +1126,9 @@ func (r *TeamRepository) TeamExists(name string) (bool, error) {
 }
 
 // GetTeamCount returns the total number of teams
-func (r *TeamRepository) GetTeamCount() (int, error) {
+func (r *TeamRepository) GetTeamCount(ctx context.Context) (int, error) {
 	var count int
-	err := DB.QueryRow("SELECT COUNT(*) FROM teams").Scan(&count)
+	err := r.db().QueryRowContext(ctx, "SELECT COUNT(*) FROM teams").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get team count: %v", err)
 	}
@@ -817,91 +1136,98 @@ func (r *TeamRepository) GetTeamCount() (int, error) {
 }
 
 // ClearTeams removes all teams from the database
-func (r *TeamRepository) ClearTeams() error {
+func (r *TeamRepository) ClearTeams(ctx context.Context) error {
 	// Start a transaction
-	tx, err := DB.Begin()
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback() // Rollback if not committed
-	
+
 	// Delete all matches first
-	_, err = tx.Exec("DELETE FROM matches")
+	_, err = tx.ExecContext(ctx, "DELETE FROM matches")
 	if err != nil {
 		return fmt.Errorf("failed to delete matches: %v", err)
 	}
-	
+
 	// Delete all team stats
-	_, err = tx.Exec("DELETE FROM team_stats")
+	_, err = tx.ExecContext(ctx, "DELETE FROM team_stats")
 	if err != nil {
 		return fmt.Errorf("failed to delete team stats: %v", err)
 	}
-	
+
+	// Delete all rating history
+	_, err = tx.ExecContext(ctx, "DELETE FROM team_ratings")
+	if err != nil {
+		return fmt.Errorf("failed to delete ratings: %v", err)
+	}
+
 	// Delete all league_teams associations
-	_, err = tx.Exec("DELETE FROM league_teams")
+	_, err = tx.ExecContext(ctx, "DELETE FROM league_teams")
 	if err != nil {
 		return fmt.Errorf("failed to delete league teams: %v", err)
 	}
-	
+
 	// Delete all leagues
-	_, err = tx.Exec("DELETE FROM leagues")
+	_, err = tx.ExecContext(ctx, "DELETE FROM leagues")
 	if err != nil {
 		return fmt.Errorf("failed to delete leagues: %v", err)
 	}
-	
+
 	// Finally delete all teams
-	_, err = tx.Exec("DELETE FROM teams")
+	_, err = tx.ExecContext(ctx, "DELETE FROM teams")
 	if err != nil {
 		return fmt.Errorf("failed to delete teams: %v", err)
 	}
-	
+
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
-	
+
 	return nil
 }
 
-// StoreFixtures stores the generated fixtures in the database
-func (r *TeamRepository) StoreFixtures(leagueID int, fixtures [][]models.Match) error {
-	// Get team IDs for mapping
-	teams, err := r.GetAllTeams()
+// storeFixtures stores the generated fixtures in the database
+func (r *TeamRepository) storeFixtures(ctx context.Context, leagueID int, fixtures [][]models.Match) error {
+	// Get team IDs of the league for mapping
+	teams, err := r.GetLeagueTeams(ctx, leagueID)
 	if err != nil {
 		return fmt.Errorf("failed to get teams: %v", err)
 	}
-	
+
 	// Create team name to ID mapping
 	teamMap := make(map[string]int)
 	for _, team := range teams {
 		teamMap[team.Name] = team.ID
 	}
-	
+
 	// Store each fixture
 	for weekIndex, weekMatches := range fixtures {
 		weekNumber := weekIndex + 1 // Convert to 1-based week numbers
 		for _, match := range weekMatches {
 			homeTeamID := teamMap[match.HomeTeam]
 			awayTeamID := teamMap[match.AwayTeam]
-			
-			_, err := DB.Exec(`
+
+			_, err := r.db().ExecContext(ctx, `
 				INSERT INTO matches (league_id, week_number, home_team_id, away_team_id, played)
 				VALUES ($1, $2, $3, $4, false)`,
 				leagueID, weekNumber, homeTeamID, awayTeamID)
 			if err != nil {
-				return fmt.Errorf("failed to store fixture: %v", err)
+				return classify(err, "failed to store fixture")
 			}
 		}
 	}
-	
+
 	return nil
 }
 
-// GetMatchSchedule gets the stored match schedule for a league
-func (r *TeamRepository) GetMatchSchedule(leagueID int) (map[int][]models.Match, error) {
+// GetMatchSchedule gets the stored fixtures of a league grouped by week,
+// each with its match ID, status and score once played
+func (r *TeamRepository) GetMatchSchedule(ctx context.Context, leagueID int) (map[int][]models.Match, error) {
 	// Get stored fixtures from database
-	rows, err := DB.Query(`
-		SELECT ht.name, at.name, m.week_number
+	rows, err := r.db().QueryContext(ctx, `
+		SELECT m.id, ht.name, at.name, m.home_score, m.away_score, m.week_number, m.played, `+cardColumns+`
 		FROM matches m
 		JOIN teams ht ON m.home_team_id = ht.id
 		JOIN teams at ON m.away_team_id = at.id
@@ -917,12 +1243,26 @@ func (r *TeamRepository) GetMatchSchedule(leagueID int) (map[int][]models.Match,
 	schedule := make(map[int][]models.Match)
 	for rows.Next() {
 		var match models.Match
-		err := rows.Scan(&match.HomeTeam, &match.AwayTeam, &match.Week)
+		var homeScore, awayScore sql.NullInt64
+		var played bool
+		err := rows.Scan(append([]interface{}{&match.ID, &match.HomeTeam, &match.AwayTeam,
+			&homeScore, &awayScore, &match.Week, &played}, cardFields(&match.Cards)...)...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan match: %v", err)
 		}
+		match.HomeScore = int(homeScore.Int64)
+		match.AwayScore = int(awayScore.Int64)
+		match.Status = matchStatus(played)
 		schedule[match.Week] = append(schedule[match.Week], match)
 	}
 
 	return schedule, nil
-} 
\ No newline at end of file
+}
+
+// matchStatus maps the played column to a match status
+func matchStatus(played bool) string {
+	if played {
+		return models.MatchPlayed
+	}
+	return models.MatchScheduled
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"insider-league/Models"
+)
+
+// sampleTeams are the teams a fresh store starts with
+var sampleTeams = []models.Team{
+	{Name: "Arsenal", Strength: 70},
+	{Name: "Chelsea", Strength: 85},
+	{Name: "Liverpool", Strength: 75},
+	{Name: "Manchester City", Strength: 92},
+}
+
+// Seed adds the sample teams that are missing. It is separate from Migrate,
+// so schema changes never touch data and seeding is a deliberate choice.
+func Seed(ctx context.Context, db *sql.DB) error {
+	return seedSampleTeams(ctx, db)
+}
+
+// seedSampleTeams inserts the sample teams, keeping existing teams of the same name
+func seedSampleTeams(ctx context.Context, q querier) error {
+	for _, team := range sampleTeams {
+		_, err := q.ExecContext(ctx, "INSERT INTO teams (name, strength) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING",
+			team.Name, team.Strength)
+		if err != nil {
+			return fmt.Errorf("failed to seed team %s: %v", team.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"insider-league/Models"
+	"insider-league/Services"
+)
+
+// restoreLeague rebuilds the in-memory simulation of a stored league from its
+// schedule, played matches, standings and rating history. Every repository
+// goes through it, so a seed plays the same season whatever the storage.
+func restoreLeague(teams []models.Team, stored models.League, schedule map[int][]models.Match,
+	played []models.Match, standings []models.TeamStats, ratings []models.TeamRating) (*services.GenerateLeague, error) {
+	engine, err := services.GetEngine(stored.Engine)
+	if err != nil {
+		return nil, err
+	}
+
+	league := services.NewSeededLeague(teams, stored.Seed)
+	league.Engine = engine
+	league.Rules = stored.Rules
+	league.RatingSystem = stored.RatingSystem
+	league.SimulateWithRatings = stored.SimulateWithRatings
+	league.CurrentWeek = stored.CurrentWeek
+
+	// Convert map to slice format expected by league
+	var fixtures [][]models.Match
+	for week := 1; week <= len(schedule); week++ {
+		if weekMatches, exists := schedule[week]; exists {
+			fixtures = append(fixtures, weekMatches)
+		}
+	}
+	league.Fixtures = fixtures
+
+	// Played matches also rebuild the form of every team
+	league.SetResults(played)
+
+	// Ratings go on from the stored ones after the current week rather than
+	// being replayed from today's strengths
+	if current := services.RatingsAfter(ratings, stored.CurrentWeek); len(current) > 0 {
+		league.SetRatings(current)
+	}
+
+	// Update in-memory stats with the stored standings
+	for _, stat := range standings {
+		if leagueStat, exists := league.TeamStats[stat.TeamName]; exists {
+			leagueStat.Played = stat.Played
+			leagueStat.Won = stat.Won
+			leagueStat.Drawn = stat.Drawn
+			leagueStat.Lost = stat.Lost
+			leagueStat.GoalsFor = stat.GoalsFor
+			leagueStat.GoalsAgainst = stat.GoalsAgainst
+			leagueStat.Points = stat.Points
+			leagueStat.GoalDiff = stat.GoalDiff
+		}
+	}
+
+	return league, nil
+}
+
+// remainingFixtures returns the fixtures of a restored league still to be
+// played, week by week. Matches of played weeks whose result was removed
+// are played with the next week, or on their own once the season is over.
+func remainingFixtures(league *services.GenerateLeague) [][]models.Match {
+	var remaining [][]models.Match
+	var weekMatches []models.Match
+	for week := 1; week <= len(league.Fixtures); week++ {
+		for _, match := range league.Fixtures[week-1] {
+			if match.Status != models.MatchPlayed {
+				weekMatches = append(weekMatches, match)
+			}
+		}
+		if week > league.CurrentWeek && len(weekMatches) > 0 {
+			remaining = append(remaining, weekMatches)
+			weekMatches = nil
+		}
+	}
+	if len(weekMatches) > 0 {
+		remaining = append(remaining, weekMatches)
+	}
+	return remaining
+}
+
+// nextWeekToPlay returns the week PlayWeek plays next: the one after the
+// current week, or the last one again once the season is over but a result
+// of it was removed. A finished season with every match played returns
+// ErrSeasonComplete.
+func nextWeekToPlay(league *services.GenerateLeague, totalWeeks int) (int, error) {
+	if league.CurrentWeek < totalWeeks {
+		return league.CurrentWeek + 1, nil
+	}
+	for _, weekMatches := range league.Fixtures {
+		for _, match := range weekMatches {
+			if match.Status != models.MatchPlayed {
+				return totalWeeks, nil
+			}
+		}
+	}
+	return 0, ErrSeasonComplete
+}
+
+// firstRatedWeek returns the week the ratings change from after a week is
+// played: the earliest week of the new results, which is before week when
+// a removed result of an earlier week was played again
+func firstRatedWeek(results []models.Match, week int) int {
+	for _, match := range results {
+		week = min(week, match.Week)
+	}
+	return week
+}
+
+// simulateWeek plays the scheduled fixtures of a week and returns the results
+// with their fixture IDs. Fixtures of earlier weeks whose result was removed
+// are played first and keep their week. Results entered by hand ahead of
+// time are kept.
+func simulateWeek(league *services.GenerateLeague, teams []models.Team, week int) ([]models.Match, error) {
+	// The random source depends only on the seed and the week being played
+	league.ReseedForWeek(week)
+
+	// Safety check to prevent index out of range
+	if week-1 >= len(league.Fixtures) {
+		return nil, fmt.Errorf("no fixtures available for week %d", week)
+	}
+
+	teamsByName := make(map[string]models.Team)
+	for _, team := range teams {
+		teamsByName[team.Name] = team
+	}
+
+	var weekMatches []models.Match
+	for fixtureWeek := 1; fixtureWeek <= week; fixtureWeek++ {
+		for _, fixture := range league.Fixtures[fixtureWeek-1] { // fixtures are 0-indexed
+			if fixture.Status == models.MatchPlayed {
+				continue
+			}
+
+			match, err := services.PlayMatch(teamsByName[fixture.HomeTeam], teamsByName[fixture.AwayTeam], league)
+			if err != nil {
+				return nil, fmt.Errorf("failed to play match: %v", err)
+			}
+
+			// The result belongs to the fixture row
+			match.ID = fixture.ID
+			match.Week = fixtureWeek
+			match.Status = models.MatchPlayed
+
+			weekMatches = append(weekMatches, match)
+		}
+	}
+
+	return weekMatches, nil
+}
+
+// playAllWeeks plays the remaining weeks of a league through repo.PlayWeek,
+// stopping early when ctx is canceled
+func playAllWeeks(ctx context.Context, repo Repository, leagueID int) ([]models.Match, error) {
+	// Get total weeks and current week for the league
+	status, err := repo.GetLeagueStatus(ctx, leagueID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Play all remaining weeks
+	var allMatches []models.Match
+	for week := status.CurrentWeek + 1; week <= status.TotalWeeks; week++ {
+		// Stop between weeks once the client has gone; played weeks are kept
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		weekMatches, err := repo.PlayWeek(ctx, leagueID)
+		if errors.Is(err, ErrSeasonComplete) {
+			break // A concurrent request played the remaining weeks
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to play week %d: %w", week, err)
+		}
+		allMatches = append(allMatches, weekMatches...)
+	}
+
+	return allMatches, nil
+}
+
+// toModelStats converts simulator stats into the stored representation
+func toModelStats(stats *services.TeamStats) models.TeamStats {
+	return models.TeamStats{
+		TeamName:     stats.TeamName,
+		Played:       stats.Played,
+		Won:          stats.Won,
+		Drawn:        stats.Drawn,
+		Lost:         stats.Lost,
+		GoalsFor:     stats.GoalsFor,
+		GoalsAgainst: stats.GoalsAgainst,
+		Points:       stats.Points,
+		GoalDiff:     stats.GoalDiff,
+	}
+}
+
+// rankTable orders stored standings by the league rules and numbers their
+// positions. played holds the league's played matches, which the
+// head-to-head and away goals tie-breakers look at.
+func rankTable(table []models.TeamStats, played []models.Match, rules models.LeagueRules, seed int64) []models.TeamStats {
+	standings := make([]*services.TeamStats, len(table))
+	for i, stats := range table {
+		standings[i] = &services.TeamStats{
+			TeamName:     stats.TeamName,
+			Played:       stats.Played,
+			Won:          stats.Won,
+			Drawn:        stats.Drawn,
+			Lost:         stats.Lost,
+			GoalsFor:     stats.GoalsFor,
+			GoalsAgainst: stats.GoalsAgainst,
+			Points:       stats.Points,
+			GoalDiff:     stats.GoalsFor - stats.GoalsAgainst,
+		}
+	}
+
+	services.RankStandings(standings, played, rules, seed)
+
+	ranked := make([]models.TeamStats, len(standings))
+	for i, stats := range standings {
+		ranked[i] = toModelStats(stats)
+		ranked[i].Position = i + 1
+	}
+	return ranked
+}
@@ -0,0 +1,95 @@
+// Package frontend embeds the web interface so the server binary is the
+// whole application
+package frontend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+//go:embed index.html script.js styles.css
+var files embed.FS
+
+// Cache policy: the page and its settings are revalidated on every load so
+// a new deployment shows up at once; the other assets may be reused for a
+// few minutes. All of them carry an ETag, so revalidating is cheap.
+const (
+	pageCacheControl  = "no-cache"
+	assetCacheControl = "public, max-age=300"
+)
+
+// asset is an embedded file ready to be served
+type asset struct {
+	name         string
+	content      []byte
+	etag         string
+	cacheControl string
+}
+
+// Register serves the interface on mux: index.html at /, the files next to
+// it under their own names, and /config.js, which tells the interface where
+// the API is. apiBase is usually "/api"; an absolute URL lets the interface
+// call an API on another host.
+func Register(mux *http.ServeMux, apiBase string) {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		panic(fmt.Sprintf("frontend: %v", err)) // The files are compiled in
+	}
+
+	for _, entry := range entries {
+		content, err := files.ReadFile(entry.Name())
+		if err != nil {
+			panic(fmt.Sprintf("frontend: %v", err))
+		}
+
+		file := newAsset(entry.Name(), content, assetCacheControl)
+		if entry.Name() == "index.html" {
+			file.cacheControl = pageCacheControl
+			mux.Handle("GET /{$}", file)
+		}
+		mux.Handle("GET /"+entry.Name(), file)
+	}
+
+	mux.Handle("GET /config.js", configScript(apiBase))
+}
+
+// configScript builds the script that hands the settings to script.js
+func configScript(apiBase string) *asset {
+	settings, err := json.Marshal(map[string]string{"apiBase": apiBase})
+	if err != nil {
+		panic(fmt.Sprintf("frontend: %v", err))
+	}
+	script := fmt.Sprintf("window.LEAGUE_CONFIG = %s;\n", settings)
+	return newAsset("config.js", []byte(script), pageCacheControl)
+}
+
+func newAsset(name string, content []byte, cacheControl string) *asset {
+	sum := sha256.Sum256(content)
+	return &asset{
+		name:         name,
+		content:      content,
+		etag:         `"` + hex.EncodeToString(sum[:8]) + `"`,
+		cacheControl: cacheControl,
+	}
+}
+
+// ServeHTTP sends the file; http.ServeContent answers If-None-Match with
+// 304 Not Modified
+func (a *asset) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Content-Type", mime.TypeByExtension(path.Ext(a.name)))
+	header.Set("Cache-Control", a.cacheControl)
+	header.Set("ETag", a.etag)
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	// Embedded files have no modification time
+	http.ServeContent(w, r, a.name, time.Time{}, bytes.NewReader(a.content))
+}
@@ -1,6 +1,7 @@
 class FootballLeagueApp {
     constructor() {
-        this.apiBase = 'http://localhost:8080/api';
+        // config.js is served by the API server and says where the API lives
+        this.apiBase = (window.LEAGUE_CONFIG && window.LEAGUE_CONFIG.apiBase) || '/api';
         this.currentWeek = 0;
         this.totalWeeks = 0;
         this.isLeagueCreated = false;
@@ -35,7 +36,7 @@ class FootballLeagueApp {
             });
 
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
 
             const data = await response.json();
@@ -58,6 +59,8 @@ class FootballLeagueApp {
     async addTeam() {
         const teamName = document.getElementById('teamName').value.trim();
         const teamStrength = parseInt(document.getElementById('teamStrength').value);
+        const teamAttack = this.optionalRating(document.getElementById('teamAttack').value);
+        const teamDefence = this.optionalRating(document.getElementById('teamDefence').value);
 
         if (!teamName) {
             this.showMessage('Please enter a team name', 'error');
@@ -69,6 +72,11 @@ class FootballLeagueApp {
             return;
         }
 
+        if (Number.isNaN(teamAttack) || Number.isNaN(teamDefence)) {
+            this.showMessage('Attack and defence must be between 1 and 100, or left empty', 'error');
+            return;
+        }
+
         try {
             this.updateStatus('Adding team...');
             this.showLoading('addTeamBtn');
@@ -80,12 +88,14 @@ class FootballLeagueApp {
                 },
                 body: JSON.stringify({
                     name: teamName,
-                    strength: teamStrength
+                    strength: teamStrength,
+                    attack: teamAttack,
+                    defence: teamDefence
                 })
             });
 
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
 
             const data = await response.json();
@@ -96,6 +106,8 @@ class FootballLeagueApp {
             // Clear form
             document.getElementById('teamName').value = '';
             document.getElementById('teamStrength').value = '';
+            document.getElementById('teamAttack').value = '';
+            document.getElementById('teamDefence').value = '';
             
             // Reload teams list
             await this.loadTeams();
@@ -113,7 +125,7 @@ class FootballLeagueApp {
         try {
             const response = await fetch(`${this.apiBase}/teams`);
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
             const data = await response.json();
             this.updateTeamsList(data.teams);
@@ -137,8 +149,9 @@ class FootballLeagueApp {
                 <h3>${team.name}</h3>
                 <p><strong>ID:</strong> ${team.id}</p>
                 <p class="team-strength"><strong>Strength:</strong> ${team.strength}/100</p>
+                ${team.attack || team.defence ? `<p class="team-strength"><strong>Attack / Defence:</strong> ${team.attack ?? team.strength} / ${team.defence ?? team.strength}</p>` : ''}
                 <div class="team-actions">
-                    <button class="btn btn-small btn-secondary edit-team-btn" data-team-id="${team.id}">Edit</button>
+                    <button class="btn btn-small btn-secondary edit-team-btn" data-team-id="${team.id}" data-attack="${team.attack ?? ''}" data-defence="${team.defence ?? ''}">Edit</button>
                     <button class="btn btn-small btn-danger delete-team-btn" data-team-id="${team.id}">Delete</button>
                 </div>
             </div>
@@ -153,7 +166,7 @@ class FootballLeagueApp {
         document.querySelectorAll('.edit-team-btn').forEach(button => {
             button.addEventListener('click', (e) => {
                 const teamId = parseInt(e.target.getAttribute('data-team-id'));
-                this.editTeam(teamId);
+                this.editTeam(teamId, e.target.getAttribute('data-attack'), e.target.getAttribute('data-defence'));
             });
         });
         
@@ -186,7 +199,7 @@ class FootballLeagueApp {
             });
             
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
             
             const data = await response.json();
@@ -207,7 +220,17 @@ class FootballLeagueApp {
         }
     }
 
-    async editTeam(teamId) {
+    // optionalRating parses an optional attack or defence rating: null when
+    // empty, NaN when it is not between 1 and 100
+    optionalRating(value) {
+        if (value === null || value.trim() === '') {
+            return null;
+        }
+        const rating = Number(value);
+        return Number.isInteger(rating) && rating >= 1 && rating <= 100 ? rating : NaN;
+    }
+
+    async editTeam(teamId, currentAttack = '', currentDefence = '') {
         // For now, we'll show a simple prompt. In a real app, you'd have a modal
         const newName = prompt('Enter new team name:');
         if (!newName) return;
@@ -221,6 +244,14 @@ class FootballLeagueApp {
             return;
         }
         
+        // Attack and defence are optional; leaving them empty clears them
+        const attack = this.optionalRating(prompt('Enter attack rating (1-100, empty for none):', currentAttack));
+        const defence = this.optionalRating(prompt('Enter defence rating (1-100, empty for none):', currentDefence));
+        if (Number.isNaN(attack) || Number.isNaN(defence)) {
+            this.showMessage('Invalid attack or defence value', 'error');
+            return;
+        }
+        
         try {
             this.updateStatus('Updating team...');
             
@@ -231,12 +262,14 @@ class FootballLeagueApp {
                 },
                 body: JSON.stringify({
                     name: newName,
-                    strength: strength
+                    strength: strength,
+                    attack: attack,
+                    defence: defence
                 })
             });
             
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
             
             const data = await response.json();
@@ -267,7 +300,7 @@ class FootballLeagueApp {
             });
             
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
             
             const data = await response.json();
@@ -292,7 +325,7 @@ class FootballLeagueApp {
 
             const response = await fetch(`${this.apiBase}/league/schedule`);
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
 
             const schedule = await response.json();
@@ -335,9 +368,14 @@ class FootballLeagueApp {
             return `
                 <div class="week-schedule" data-week="${week}">
                     <div class="week-header">Week ${week}</div>
-                    ${matches.map(match => `
+                    ${matches.map(match => match.status === 'played' ? `
+                        <div class="schedule-match played">
+                            <div class="match-teams">${match.home_team} ${match.home_score} - ${match.away_score} ${match.away_team}</div>
+                            <div class="match-status played">Played</div>
+                        </div>
+                    ` : `
                         <div class="schedule-match upcoming">
-                            <div class="match-teams">${match.HomeTeam} vs ${match.AwayTeam}</div>
+                            <div class="match-teams">${match.home_team} vs ${match.away_team}</div>
                             <div class="match-status upcoming">Upcoming</div>
                         </div>
                     `).join('')}
@@ -386,11 +424,12 @@ class FootballLeagueApp {
                 headers: {
                     'Content-Type': 'application/json'
                 },
-                body: JSON.stringify([])
+                // Empty options: every team, double round robin
+                body: JSON.stringify({})
             });
 
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
 
             const data = await response.json();
@@ -432,7 +471,7 @@ class FootballLeagueApp {
             });
 
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
 
             this.isLeagueCreated = false;
@@ -473,7 +512,7 @@ class FootballLeagueApp {
             });
 
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
 
             const data = await response.json();
@@ -514,7 +553,7 @@ class FootballLeagueApp {
             });
 
             if (!response.ok) {
-                throw new Error(`HTTP error! status: ${response.status}`);
+                throw await this.responseError(response);
             }
 
             const data = await response.json();
@@ -592,8 +631,9 @@ class FootballLeagueApp {
         try {
             const response = await fetch(`${this.apiBase}/league/predictions`);
             if (response.ok) {
-                const predictions = await response.json();
-                this.updatePredictionsFromBackend(predictions);
+                const data = await response.json();
+                // Backend wraps predictions with the number of simulations used
+                this.updatePredictionsFromBackend(data.predictions || []);
             }
         } catch (error) {
             console.error('Error loading predictions:', error);
@@ -606,7 +646,7 @@ class FootballLeagueApp {
         const tbody = document.getElementById('leagueTableBody');
         
         if (!standings || standings.length === 0) {
-            tbody.innerHTML = '<tr><td colspan="8" class="no-data">No league data available</td></tr>';
+            tbody.innerHTML = '<tr><td colspan="9" class="no-data">No league data available</td></tr>';
             return;
         }
 
@@ -626,6 +666,7 @@ class FootballLeagueApp {
                 <td>${team.lost || 0}</td>
                 <td class="goal-diff ${(team.goal_difference || 0) >= 0 ? 'positive' : 'negative'}">${(team.goal_difference || 0) >= 0 ? '+' : ''}${team.goal_difference || 0}</td>
                 <td>${team.goals_for || 0}</td>
+                <td class="form" title="Form rating ${(team.form_rating || 0).toFixed(2)}">${team.form || '-'}</td>
             </tr>
         `).join('');
     }
@@ -848,6 +889,21 @@ class FootballLeagueApp {
         }
     }
 
+    // responseError turns a failed response into an Error carrying the
+    // detail and code of the API's problem+json body
+    async responseError(response) {
+        let problem = {};
+        try {
+            problem = await response.json();
+        } catch (e) {
+            // Not a problem body, fall back to the status
+        }
+        const error = new Error(problem.detail || problem.title || `HTTP error! status: ${response.status}`);
+        error.code = problem.code;
+        error.status = response.status;
+        return error;
+    }
+
     updateStatus(message) {
         document.getElementById('statusMessage').textContent = message;
     }
@@ -1,33 +1,135 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"math/rand"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
+
+	handlers "insider-league/Handlers"
+	"insider-league/Services"
+	"insider-league/config"
 	"insider-league/database"
 )
 
+// version is the build version, set at link time with
+// -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
 func main() {
-	// Seed random number generator
-	rand.Seed(time.Now().UnixNano())
-	
-	// Connect to database
-	if err := database.Connect(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer database.Close()
-	
-	fmt.Println("Database connected successfully!")
-	
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Every new league starts with the configured simulation parameters
+	services.DefaultSimulationParams = services.SimulationParams{
+		HomeAdvantage: cfg.Simulation.HomeAdvantage,
+		FormBonus:     cfg.Simulation.FormBonus,
+		FormWindow:    cfg.Simulation.FormWindow,
+		FormDecay:     cfg.Simulation.FormDecay,
+	}
+	services.DefaultEloParams = services.EloParams{
+		KFactor:       cfg.Simulation.EloKFactor,
+		HomeAdvantage: cfg.Simulation.EloHomeAdvantage,
+	}
+
+	// Stop on Ctrl+C or SIGTERM, the signal sent by Heroku and container runtimes
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var repo database.Repository
+	var db *sql.DB // stays nil for in-memory storage
+	if cfg.Memory {
+		repo = database.NewMemoryRepository()
+		fmt.Println("Using in-memory storage, data is lost when the server stops")
+	} else {
+		// Connect to database
+		err := database.Connect(database.Options{
+			DSN:             cfg.Database.URL,
+			MaxOpenConns:    cfg.Database.MaxOpenConns,
+			MaxIdleConns:    cfg.Database.MaxIdleConns,
+			ConnMaxLifetime: cfg.Database.ConnMaxLifetime.Duration,
+		})
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer database.Close()
+
+		fmt.Println("Database connected successfully!")
+
+		// Bring the schema up to date before serving requests
+		if err := database.Migrate(database.DB); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+
+		if cfg.Seed {
+			if err := database.Seed(ctx, database.DB); err != nil {
+				log.Fatalf("Failed to seed database: %v", err)
+			}
+		}
+
+		repo = &database.TeamRepository{}
+		db = database.DB
+	}
+
 	// Setup routes
-	SetupRoutes()
-	
+	health := handlers.NewHealthHandler(db, version)
+	router := NewRouter(repo, health, cfg.AllowedOrigins, cfg.APIBase, cfg.Server.WriteTimeout.Duration)
+
+	// Requests run under baseCtx, which is only canceled when they outlast
+	// the shutdown timeout
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	server := &http.Server{
+		Addr:         cfg.ListenAddr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout.Duration,
+		WriteTimeout: cfg.Server.WriteTimeout.Duration,
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+	}
+
 	// Start server
-	port := ":8080"
-	fmt.Println(" Football League API Server starting on port %s\n", port)
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Printf(" Football League API Server %s starting on %s\n", version, cfg.ListenAddr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Fatalf("Server failed: %v", err)
+	case <-ctx.Done():
+	}
+	stop() // A second signal stops the process at once
+
+	// Stop accepting connections and let running requests finish
+	fmt.Println("Shutting down, waiting for running requests to finish...")
+	shutdownCtx := context.Background()
+	if timeout := cfg.Server.ShutdownTimeout.Duration; timeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, timeout)
+		defer cancel()
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Requests still running after %s, canceling them", cfg.Server.ShutdownTimeout)
+		cancelRequests()
+		server.Close()
+	}
 
-	
-	log.Fatal(http.ListenAndServe(port, nil))
+	fmt.Println("Server stopped")
 }
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORS lets browsers on the allowed origins call the API; "*" allows any
+// origin. Preflight requests are answered here and never reach the router.
+func CORS(origins []string) Middleware {
+	allowed := make(map[string]bool)
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			origin := r.Header.Get("Origin")
+
+			if allowed["*"] {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				// The response depends on the origin, so caches must key on it
+				header.Add("Vary", "Origin")
+				if allowed[origin] {
+					header.Set("Access-Control-Allow-Origin", origin)
+				}
+			}
+			header.Set("Access-Control-Expose-Headers", RequestIDHeader)
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				header.Set("Access-Control-Allow-Methods", strings.Join([]string{
+					http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions,
+				}, ", "))
+				header.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+RequestIDHeader)
+				header.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"insider-league/Models"
+)
+
+// Middleware wraps a handler with extra behaviour
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with the middlewares; the first one is the outermost and
+// sees every request first
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestID gives every request an ID, reusing a sensible one sent by the
+// client, and echoes it in the response
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFrom returns the ID given to the request by RequestID
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts short IDs made of letters, digits, '-' and '_'
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 32 character hex ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// Logger writes one access log line per request
+func Logger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		log.Printf("%s %s %d %dB %s request_id=%s",
+			r.Method, r.URL.RequestURI(), rec.statusCode(), rec.bytes,
+			time.Since(start).Round(time.Microsecond), RequestIDFrom(r.Context()))
+	})
+}
+
+// Recover turns a panicking handler into a 500 response instead of a
+// dropped connection, and logs the stack trace
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err) // Deliberate abort, let net/http handle it
+			}
+
+			log.Printf("panic serving %s %s request_id=%s: %v\n%s",
+				r.Method, r.URL.Path, RequestIDFrom(r.Context()), err, debug.Stack())
+
+			// Too late to change the response once it has started
+			if rec.status == 0 {
+				problem := models.NewProblem(http.StatusInternalServerError, "internal_error", "Internal server error")
+				problem.Instance = r.URL.Path
+				problem.RequestID = RequestIDFrom(r.Context())
+
+				rec.Header().Set("Content-Type", "application/problem+json")
+				rec.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(rec).Encode(problem)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// statusRecorder remembers the status code and body size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// statusCode is the status sent, 200 when the handler wrote nothing
+func (rec *statusRecorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
@@ -1,362 +1,90 @@
 package main
 
 import (
-	"encoding/json"
 	handlers "insider-league/Handlers"
+	"insider-league/database"
+	"insider-league/frontend"
+	"insider-league/middleware"
 	"net/http"
-	"strings"
+	"time"
 )
 
-func SetupRoutes() {
+// NewRouter registers every API route and wraps them in the middleware
+// chain. Routes use method and wildcard patterns, so handlers read path
+// parameters with r.PathValue and never see a request with the wrong method.
+// Unknown paths and methods are answered with problem responses. The web
+// interface is served at / and finds the API through apiBase. Simulations
+// are stopped shortly before writeTimeout, when the response could no longer
+// reach the client.
+func NewRouter(repo database.Repository, health *handlers.HealthHandler, allowedOrigins []string, apiBase string, writeTimeout time.Duration) http.Handler {
 	// Create league handler
-	leagueHandler := handlers.NewLeagueHandler()
-	
-	http.HandleFunc("/api/league", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodPost:
-			leagueHandler.CreateLeague(w, r)
-		case http.MethodDelete:
-			leagueHandler.ClearLeague(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	http.HandleFunc("/api/league/play-week", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodPost:
-			leagueHandler.PlayWeek(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	http.HandleFunc("/api/league/play-all", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodPost:
-			leagueHandler.PlayAllWeeks(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	http.HandleFunc("/api/league/table", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodGet:
-			leagueHandler.GetLeagueTable(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	http.HandleFunc("/api/league/matches", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodGet:
-			leagueHandler.GetMatches(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	http.HandleFunc("/api/league/matches/week/", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodGet:
-			leagueHandler.GetWeekMatches(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	http.HandleFunc("/api/league/status", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodGet:
-			leagueHandler.GetLeagueStatus(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	http.HandleFunc("/api/league/clear", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodDelete:
-			leagueHandler.ClearLeague(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	// Initialize database endpoint
-	http.HandleFunc("/api/init-db", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodPost:
-			leagueHandler.InitializeDatabase(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
+	leagueHandler := handlers.NewLeagueHandler(repo, writeTimeout*9/10)
 
-	// Clear teams endpoint
-	http.HandleFunc("/api/clear-teams", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodDelete:
-			leagueHandler.ClearTeams(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	// Get match schedule endpoint
-	http.HandleFunc("/api/league/schedule", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodGet:
-			leagueHandler.GetMatchSchedule(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
-	// Team management endpoints (combined)
-	http.HandleFunc("/api/teams", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
+	mux := http.NewServeMux()
 
-		
-		// Check if this is an individual team operation (has ID in path)
-		path := strings.TrimPrefix(r.URL.Path, "/api/teams")
-		path = strings.TrimPrefix(path, "/")
-		if path != "" && !strings.Contains(path, "/") {
-			// This is an individual team operation
-			switch r.Method {
-			case http.MethodGet:
-				leagueHandler.GetTeam(w, r)
-			case http.MethodPut:
-				leagueHandler.UpdateTeam(w, r)
-			case http.MethodDelete:
-				leagueHandler.DeleteTeam(w, r)
-			default:
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
-			return
-		}
-		
-		// This is a general team operation
-		switch r.Method {
-		case http.MethodGet:
-			leagueHandler.GetTeams(w, r)
-		case http.MethodPost:
-			leagueHandler.AddTeam(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-	
+	// Default league; these routes are aliases for /api/leagues/{id}/...
+	mux.HandleFunc("POST /api/league", leagueHandler.CreateLeague)
+	mux.HandleFunc("DELETE /api/league", leagueHandler.ClearLeague)
+	mux.HandleFunc("DELETE /api/league/clear", leagueHandler.ClearLeague)
+	mux.HandleFunc("GET /api/league/status", leagueHandler.GetLeagueStatus)
+	mux.HandleFunc("POST /api/league/play-week", leagueHandler.PlayWeek)
+	mux.HandleFunc("POST /api/league/play-all", leagueHandler.PlayAllWeeks)
+	mux.HandleFunc("POST /api/league/rewind", leagueHandler.RewindLeague)
+	mux.HandleFunc("GET /api/league/table", leagueHandler.GetLeagueTable)
+	mux.HandleFunc("GET /api/league/matches", leagueHandler.GetMatches)
+	mux.HandleFunc("GET /api/league/matches/week/{week}", leagueHandler.GetWeekMatches)
+	mux.HandleFunc("PUT /api/league/matches/{matchID}", leagueHandler.UpdateMatch)
+	mux.HandleFunc("DELETE /api/league/matches/{matchID}", leagueHandler.DeleteMatchResult)
+	mux.HandleFunc("GET /api/league/schedule", leagueHandler.GetMatchSchedule)
+	mux.HandleFunc("GET /api/league/predictions", leagueHandler.GetChampionshipPredictions)
+	mux.HandleFunc("GET /api/league/ratings", leagueHandler.GetRatings)
 
-	
-	// Debug endpoint
-	http.HandleFunc("/api/debug", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		response := map[string]string{"message": "Debug endpoint working"}
-		json.NewEncoder(w).Encode(response)
-	})
+	// Leagues by ID
+	mux.HandleFunc("GET /api/leagues", leagueHandler.ListLeagues)
+	mux.HandleFunc("POST /api/leagues", leagueHandler.AddLeague)
+	mux.HandleFunc("GET /api/leagues/{id}", leagueHandler.GetLeagueStatus)
+	mux.HandleFunc("DELETE /api/leagues/{id}", leagueHandler.ClearLeague)
+	mux.HandleFunc("GET /api/leagues/{id}/status", leagueHandler.GetLeagueStatus)
+	mux.HandleFunc("POST /api/leagues/{id}/default", leagueHandler.SetDefaultLeague)
+	mux.HandleFunc("POST /api/leagues/{id}/play-week", leagueHandler.PlayWeek)
+	mux.HandleFunc("POST /api/leagues/{id}/play-all", leagueHandler.PlayAllWeeks)
+	mux.HandleFunc("POST /api/leagues/{id}/rewind", leagueHandler.RewindLeague)
+	mux.HandleFunc("GET /api/leagues/{id}/table", leagueHandler.GetLeagueTable)
+	mux.HandleFunc("GET /api/leagues/{id}/matches", leagueHandler.GetMatches)
+	mux.HandleFunc("GET /api/leagues/{id}/matches/week/{week}", leagueHandler.GetWeekMatches)
+	mux.HandleFunc("PUT /api/leagues/{id}/matches/{matchID}", leagueHandler.UpdateMatch)
+	mux.HandleFunc("DELETE /api/leagues/{id}/matches/{matchID}", leagueHandler.DeleteMatchResult)
+	mux.HandleFunc("GET /api/leagues/{id}/schedule", leagueHandler.GetMatchSchedule)
+	mux.HandleFunc("GET /api/leagues/{id}/predictions", leagueHandler.GetChampionshipPredictions)
+	mux.HandleFunc("GET /api/leagues/{id}/ratings", leagueHandler.GetRatings)
 
-	// Championship predictions endpoint
-	http.HandleFunc("/api/league/predictions", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Content-Type", "application/json")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodGet:
-			leagueHandler.GetChampionshipPredictions(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
+	// Teams
+	mux.HandleFunc("GET /api/teams", leagueHandler.GetTeams)
+	mux.HandleFunc("POST /api/teams", leagueHandler.AddTeam)
+	mux.HandleFunc("GET /api/teams/{id}", leagueHandler.GetTeam)
+	mux.HandleFunc("PUT /api/teams/{id}", leagueHandler.UpdateTeam)
+	mux.HandleFunc("DELETE /api/teams/{id}", leagueHandler.DeleteTeam)
 
-	// Championship predictions endpoint (moved here for testing)
-	http.HandleFunc("/api/predictions-test", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		testResponse := []map[string]interface{}{
-			{
-				"team_name":  "Chelsea",
-				"percentage": 45.0,
-				"points":     0,
-				"position":   1,
-			},
-		}
-		json.NewEncoder(w).Encode(testResponse)
-	})
+	// Data management
+	mux.HandleFunc("POST /api/init-db", leagueHandler.InitializeDatabase)
+	mux.HandleFunc("DELETE /api/clear-teams", leagueHandler.ClearTeams)
 
-	// Health endpoint
-	http.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		switch r.Method {
-		case http.MethodGet:
-			
-			healthStatus := map[string]interface{}{
-				"status":    "OK",
-				"message":   "Football League API is running",
-				"timestamp": "2024-01-15T10:30:00Z",
-				"version":   "1.0.0",
-			}
-			
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			
-			response, _ := json.Marshal(healthStatus)
-			w.Write(response)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
+	// Match engines endpoint
+	mux.HandleFunc("GET /api/engines", leagueHandler.GetEngines)
+
+	// Health probes; /api/health is kept as an alias for liveness
+	mux.HandleFunc("GET /api/health", health.Live)
+	mux.HandleFunc("GET /api/health/live", health.Live)
+	mux.HandleFunc("GET /api/health/ready", health.Ready)
+
+	// Web interface
+	frontend.Register(mux, apiBase)
+
+	return middleware.Chain(handlers.WithProblemFallback(mux),
+		middleware.RequestID,
+		middleware.Logger,
+		middleware.Recover,
+		middleware.CORS(allowedOrigins),
+		middleware.Gzip,
+	)
 }
@@ -0,0 +1,184 @@
+// Package validation checks request models against their validate struct
+// tags. A tag is a comma-separated list of rules:
+//
+//	required   the field must be set: non-nil, non-zero, or non-blank for strings
+//	omitempty  skip the other rules when the field has its zero value
+//	min=N      numbers must be at least N, strings, slices and maps at least N long
+//	max=N      numbers must be at most N, strings, slices and maps at most N long
+//	oneof=A B  the value must be one of the space-separated values
+//
+// Pointers are checked through to the value they point at; a nil pointer
+// only fails required. Fields are reported by their JSON name. Fields that
+// hold a struct are checked as well, their fields reported as parent.field.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes one field that broke a rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors lists every field error found in a value
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + " " + fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Struct checks the fields of a struct, or a pointer to one, against their
+// validate tags. It returns nil when every rule holds and Errors otherwise.
+// A malformed tag panics, since it is a programming error.
+func Struct(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: %T is not a struct", v))
+	}
+
+	if errs := checkStruct("", value); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkStruct checks the fields of a struct value, naming them after prefix
+func checkStruct(prefix string, value reflect.Value) Errors {
+	var errs Errors
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + jsonName(field)
+
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			if fieldErr := checkField(name, value.Field(i), tag); fieldErr != nil {
+				errs = append(errs, *fieldErr)
+				continue
+			}
+		}
+		if nested := reflect.Indirect(value.Field(i)); nested.Kind() == reflect.Struct {
+			errs = append(errs, checkStruct(name+".", nested)...)
+		}
+	}
+	return errs
+}
+
+// checkField applies the rules of one tag and returns the first one broken
+func checkField(name string, value reflect.Value, tag string) *FieldError {
+	rules := strings.Split(tag, ",")
+
+	for _, rule := range rules {
+		if rule == "omitempty" && value.IsZero() {
+			return nil
+		}
+	}
+
+	for _, rule := range rules {
+		rule, arg, _ := strings.Cut(rule, "=")
+		switch rule {
+		case "omitempty":
+		case "required":
+			if isBlank(value) {
+				return &FieldError{Field: name, Rule: rule, Message: "is required"}
+			}
+		case "min", "max", "oneof":
+			target := reflect.Indirect(value)
+			if !target.IsValid() {
+				continue // A nil pointer is only checked by required
+			}
+			if message := checkRule(target, rule, arg); message != "" {
+				return &FieldError{Field: name, Rule: rule, Message: message}
+			}
+		default:
+			panic(fmt.Sprintf("validation: unknown rule %q on field %s", rule, name))
+		}
+	}
+	return nil
+}
+
+// checkRule applies min, max or oneof to a value and returns what is wrong
+// with it, or "" when the rule holds
+func checkRule(value reflect.Value, rule, arg string) string {
+	if rule == "oneof" {
+		options := strings.Fields(arg)
+		actual := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if actual == option {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(options, ", ")
+	}
+
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: bad %s argument %q", rule, arg))
+	}
+
+	var actual float64
+	var unit string
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+	case reflect.String:
+		actual, unit = float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual, unit = float64(value.Len()), " items"
+	default:
+		panic(fmt.Sprintf("validation: %s does not apply to %s", rule, value.Kind()))
+	}
+
+	if rule == "min" && actual < limit {
+		if unit != "" {
+			return fmt.Sprintf("must be at least %s%s long", arg, unit)
+		}
+		return "must be at least " + arg
+	}
+	if rule == "max" && actual > limit {
+		if unit != "" {
+			return fmt.Sprintf("must be at most %s%s long", arg, unit)
+		}
+		return "must be at most " + arg
+	}
+	return ""
+}
+
+// isBlank reports whether a required field is missing
+func isBlank(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// jsonName returns the name a field has in JSON
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}